@@ -0,0 +1,94 @@
+package nicehttp
+
+import (
+	"math/rand"
+	"time"
+)
+
+var (
+	_ Backoff = ConstantBackoff(0)
+	_ Backoff = ExponentialBackoff{}
+	_ Backoff = JitterBackoff{}
+)
+
+// Backoff decides how long to wait before a failed request is retried. NextDelay is provided the number of the retry
+// about to be made, starting from 1 for the first retry of a request. Implementations must be safe to use from
+// multiple goroutines at once, as a single Backoff is consulted by every worker of every download made by a Client.
+type Backoff interface {
+	NextDelay(attempt int) time.Duration
+}
+
+// ConstantBackoff waits for the same duration before every retry.
+type ConstantBackoff time.Duration
+
+// NextDelay returns b regardless of attempt.
+func (b ConstantBackoff) NextDelay(int) time.Duration {
+	return time.Duration(b)
+}
+
+// ExponentialBackoff waits for Base before the first retry, and doubles the duration waited for before every retry
+// after it up to Max. A Max of zero or less means the duration waited for is not capped.
+type ExponentialBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// NextDelay returns Base doubled attempt-1 times, capped to Max.
+func (b ExponentialBackoff) NextDelay(attempt int) time.Duration {
+	delay := b.Base
+	if delay <= 0 {
+		return 0
+	}
+
+	for i := 1; i < attempt; i++ {
+		if b.Max > 0 && delay >= b.Max {
+			break
+		}
+		if delay > maxDuration/2 {
+			delay = maxDuration
+			break
+		}
+		delay *= 2
+	}
+
+	if b.Max > 0 && delay > b.Max {
+		delay = b.Max
+	}
+
+	return delay
+}
+
+// JitterBackoff waits for a random duration between zero and the duration an ExponentialBackoff with the same Base
+// and Max would wait for, otherwise known as "full jitter". Spreading retries out at random keeps many downloads that
+// fail at once against the same origin from all retrying against it at once.
+type JitterBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// NextDelay returns a random duration in [0, d], where d is the duration returned by an ExponentialBackoff with the
+// same Base and Max.
+func (b JitterBackoff) NextDelay(attempt int) time.Duration {
+	delay := ExponentialBackoff(b).NextDelay(attempt)
+	if delay <= 0 {
+		return 0
+	}
+	if delay == maxDuration {
+		return time.Duration(rand.Int63n(int64(delay)))
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// maxDuration is the longest representable time.Duration.
+const maxDuration = time.Duration(1<<63 - 1)
+
+// defaultBackoff is the Backoff consulted by clients whose Backoff is nil.
+var defaultBackoff Backoff = JitterBackoff{Base: 10 * time.Millisecond, Max: time.Second}
+
+// backoff returns the Backoff consulted by c before retrying a request.
+func (c *Client) backoff() Backoff {
+	if c.Backoff == nil {
+		return defaultBackoff
+	}
+	return c.Backoff
+}
@@ -0,0 +1,144 @@
+package nicehttp
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DownloadJob describes a URL whose contents are to be downloaded into a file by DownloadFiles.
+type DownloadJob struct {
+	// The URL whose contents are to be downloaded.
+	URL string
+
+	// The name of the file the contents of URL are to be written to.
+	Filename string
+
+	// Decide whether or not the contents of URL are to be downloaded serially without first querying its headers,
+	// which saves a HEAD request for URLs that are known to be small.
+	Serial bool
+
+	// Length of the contents of URL, i.e. as listed in a manifest. Should it be positive, the headers of URL are not
+	// queried, and its contents are downloaded using DownloadFileSized.
+	ContentLength int
+
+	// Decide whether or not URL accepts requests for byte ranges. It is only used should ContentLength be positive.
+	AcceptsRanges bool
+}
+
+// DownloadFiles downloads the contents of the URLs of jobs into their respective files, with at most
+// c.MaxConcurrentDownloads jobs being downloaded at once. It returns the errors encountered by each job, in the same
+// order as jobs. An error is nil should its job have succeeded.
+//
+// Should c.OnBatchProgress be set, it is called with the progress made across all jobs as any of them make progress.
+func (c *Client) DownloadFiles(jobs []DownloadJob) []error {
+	return c.DownloadFilesDeadline(jobs, zeroTime)
+}
+
+// DownloadFilesTimeout downloads the contents of the URLs of jobs into their respective files, with at most
+// c.MaxConcurrentDownloads jobs being downloaded at once. It returns the errors encountered by each job.
+func (c *Client) DownloadFilesTimeout(jobs []DownloadJob, timeout time.Duration) []error {
+	return c.DownloadFilesDeadline(jobs, c.clock().Add(timeout))
+}
+
+// DownloadFilesDeadline downloads the contents of the URLs of jobs into their respective files, with at most
+// c.MaxConcurrentDownloads jobs being downloaded at once. It returns the errors encountered by each job.
+func (c *Client) DownloadFilesDeadline(jobs []DownloadJob, deadline time.Time) []error {
+	errs := make([]error, len(jobs))
+
+	n := c.MaxConcurrentDownloads
+	if n <= 0 {
+		n = 1
+	}
+
+	sem := make(chan struct{}, n)
+
+	var progress *batchProgress
+	if c.OnBatchProgress != nil {
+		progress = &batchProgress{report: c.OnBatchProgress}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(jobs))
+
+	for i := range jobs {
+		i := i
+
+		sem <- struct{}{}
+
+		go func() {
+			defer func() { <-sem }()
+			defer wg.Done()
+
+			job := jobs[i]
+
+			c := c
+			if progress != nil {
+				cc := *c
+				cc.emit = progress.track(c.emit)
+				c = &cc
+			}
+
+			switch {
+			case job.Serial:
+				errs[i] = c.downloadFile(job.Filename, job.URL, 0, false, deadline, downloadOptions{})
+			case job.ContentLength > 0:
+				errs[i] = c.DownloadFileSizedDeadline(job.Filename, job.URL, job.ContentLength, job.AcceptsRanges, deadline)
+			default:
+				errs[i] = c.DownloadFileDeadline(job.Filename, job.URL, deadline)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return errs
+}
+
+// batchProgress aggregates the progress of all jobs of a batch, and reports it to a hook.
+type batchProgress struct {
+	done   int64
+	total  int64
+	report func(done, total int64)
+}
+
+// track returns a hook for the download events of a single job which accounts for its progress, and forwards events
+// to next should it not be nil.
+func (p *batchProgress) track(next func(DownloadEvent)) func(DownloadEvent) {
+	var length, done int64
+
+	return func(e DownloadEvent) {
+		var total int64
+
+		switch e := e.(type) {
+		case Started:
+			total = int64(e.ContentLength)
+		case Progress:
+			total = e.Total
+		}
+
+		if total > 0 && atomic.CompareAndSwapInt64(&length, 0, total) {
+			p.report(atomic.LoadInt64(&p.done), atomic.AddInt64(&p.total, total))
+		}
+
+		// Progress events carry the number of bytes downloaded by a job so far, and may be emitted by its workers out
+		// of order. Only the bytes downloaded past the most that has been reported so far are accounted for.
+
+		if e, ok := e.(Progress); ok && atomic.LoadInt64(&length) > 0 {
+			for {
+				prev := atomic.LoadInt64(&done)
+				if e.Done <= prev {
+					break
+				}
+				if atomic.CompareAndSwapInt64(&done, prev, e.Done) {
+					p.report(atomic.AddInt64(&p.done, e.Done-prev), atomic.LoadInt64(&p.total))
+					break
+				}
+			}
+		}
+
+		if next != nil {
+			next(e)
+		}
+	}
+}
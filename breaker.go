@@ -0,0 +1,156 @@
+package nicehttp
+
+import (
+	"errors"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned should a request be made to a host whose circuit has been opened by the CircuitBreaker
+// of a Client.
+var ErrCircuitOpen = errors.New("circuit is open")
+
+// CircuitBreaker decides whether or not requests may be sent to a host based on how previous requests sent to it
+// fared. Allow is called before every request is sent, and requests it disallows fail with ErrCircuitOpen without
+// being sent. Every request that Allow permits is followed by exactly one call to Report once the request completes,
+// with failed set should the request have failed or have been responded to with a status code of 5xx.
+//
+// Implementations must be safe to use from multiple goroutines at once, as a single CircuitBreaker is consulted by
+// every worker of every download made by a Client.
+type CircuitBreaker interface {
+	Allow(host string) bool
+	Report(host string, failed bool)
+}
+
+var _ CircuitBreaker = (*HostCircuitBreaker)(nil)
+
+// HostCircuitBreaker is a CircuitBreaker which opens the circuit of a host after Threshold consecutive requests to
+// it fail within Window of the first of them. Requests to a host whose circuit is open fail fast until Cooldown has
+// passed, after which a single request is let through as a probe. Should the probe succeed, the circuit is closed.
+// Otherwise, it is opened for another Cooldown.
+//
+// A zero Window means consecutive failures are counted regardless of how far apart they are. A Threshold of zero or
+// less means circuits are never opened.
+type HostCircuitBreaker struct {
+	Threshold int
+	Window    time.Duration
+	Cooldown  time.Duration
+
+	// Clock which the current time is read from. Defaults to the system clock should it be nil.
+	Clock Clock
+
+	mu    sync.Mutex
+	hosts map[string]*hostCircuit
+}
+
+// hostCircuit is the state of the circuit of a single host.
+type hostCircuit struct {
+	failures  int
+	firstFail time.Time
+	openUntil time.Time
+	open      bool
+	probing   bool
+}
+
+// NewHostCircuitBreaker instantiates a HostCircuitBreaker which opens the circuit of a host for cooldown after
+// threshold consecutive requests to it fail within window.
+func NewHostCircuitBreaker(threshold int, window, cooldown time.Duration) *HostCircuitBreaker {
+	return &HostCircuitBreaker{Threshold: threshold, Window: window, Cooldown: cooldown}
+}
+
+// now returns the current time as reported by b.Clock, or by time.Now should b.Clock be nil.
+func (b *HostCircuitBreaker) now() time.Time {
+	if b.Clock != nil {
+		return b.Clock.Now()
+	}
+	return time.Now()
+}
+
+// Allow reports whether or not a request may be sent to host.
+func (b *HostCircuitBreaker) Allow(host string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	h, exists := b.hosts[host]
+	if !exists || !h.open {
+		return true
+	}
+
+	if h.probing || b.now().Before(h.openUntil) {
+		return false
+	}
+
+	h.probing = true
+
+	return true
+}
+
+// Report records whether or not a request sent to host has failed.
+func (b *HostCircuitBreaker) Report(host string, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !failed {
+		delete(b.hosts, host)
+		return
+	}
+
+	if b.Threshold <= 0 {
+		return
+	}
+
+	if b.hosts == nil {
+		b.hosts = make(map[string]*hostCircuit)
+	}
+
+	h, exists := b.hosts[host]
+	if !exists {
+		h = new(hostCircuit)
+		b.hosts[host] = h
+	}
+
+	now := b.now()
+
+	if h.open {
+		// Either the probe failed, or a request let through before the circuit was opened failed.
+
+		if h.probing {
+			h.probing = false
+			h.openUntil = now.Add(b.Cooldown)
+		}
+
+		return
+	}
+
+	if h.failures == 0 || (b.Window > 0 && now.Sub(h.firstFail) > b.Window) {
+		h.failures = 0
+		h.firstFail = now
+	}
+
+	if h.failures++; h.failures >= b.Threshold {
+		h.open = true
+		h.openUntil = now.Add(b.Cooldown)
+	}
+}
+
+// sendThroughBreaker sends req using c.send should c.CircuitBreaker allow for a request to be sent to the host of req,
+// and reports the outcome of the request back to c.CircuitBreaker.
+func (c *Client) sendThroughBreaker(req *fasthttp.Request, res *fasthttp.Response, deadline time.Time) error {
+	if c.CircuitBreaker == nil {
+		return c.send(req, res, deadline)
+	}
+
+	host := string(req.URI().Host())
+
+	if !c.CircuitBreaker.Allow(host) {
+		return fmt.Errorf("failed to send request to %q: %w", host, ErrCircuitOpen)
+	}
+
+	err := c.send(req, res, deadline)
+
+	c.CircuitBreaker.Report(host, err != nil || res.StatusCode() >= fasthttp.StatusInternalServerError)
+
+	return err
+}
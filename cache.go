@@ -0,0 +1,117 @@
+package nicehttp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"time"
+)
+
+// CacheEntry is the contents of a URL stored in a Cache, alongside the validator and freshness lifetime they were
+// served with.
+type CacheEntry struct {
+	// The contents of the URL. It must not be modified once the entry has been stored in a Cache.
+	Body []byte
+
+	// The ETag the contents were served with, if any. Stale entries with an ETag are revalidated using If-None-Match.
+	ETag string
+
+	// The time after which the entry is stale, and must be revalidated before being served.
+	Expires time.Time
+}
+
+// Cache stores the contents of URLs downloaded by Client.DownloadBytes, keyed by URL. Implementations must be safe to
+// use from multiple goroutines at once. Package github.com/lithdew/nicehttp/lru provides a size-bounded LRU Cache.
+type Cache interface {
+	Get(url string) (CacheEntry, bool)
+	Set(url string, entry CacheEntry)
+}
+
+// downloadBytesCached serves the contents of url from c.Cache should they be fresh, revalidates them should they be
+// stale, and otherwise downloads them and stores them in c.Cache.
+func (c *Client) downloadBytesCached(dst []byte, url string, deadline time.Time, o downloadOptions) ([]byte, error) {
+	entry, cached := c.Cache.Get(url)
+
+	if cached && c.clock().Before(entry.Expires) {
+		return o.cachedBytes(dst, url, entry)
+	}
+
+	if cached && entry.ETag != "" {
+		o.ifNoneMatch = entry.ETag
+	}
+
+	header := new(fasthttp.ResponseHeader)
+
+	start := len(dst)
+
+	buf, err := c.fetchBytes(dst, url, deadline, header, o)
+	if errors.Is(err, ErrNotModified) && cached {
+		if expires, ok := cacheExpiry(header, c.clock()); ok {
+			entry.Expires = expires
+			c.Cache.Set(url, entry)
+		}
+		return o.cachedBytes(dst, url, entry)
+	}
+	if err != nil {
+		return buf, err
+	}
+
+	expires, ok := cacheExpiry(header, c.clock())
+	if !ok {
+		return buf, nil
+	}
+
+	etag := string(header.Peek("ETag"))
+	if etag == "" && !expires.After(c.clock()) {
+		return buf, nil
+	}
+
+	c.Cache.Set(url, CacheEntry{Body: append([]byte(nil), buf[start:]...), ETag: etag, Expires: expires})
+
+	return buf, nil
+}
+
+// cachedBytes appends the contents of url stored in entry to dst, subject to the options prescribed in o.
+func (o downloadOptions) cachedBytes(dst []byte, url string, entry CacheEntry) ([]byte, error) {
+	if o.maxBodySize > 0 && len(entry.Body) > o.maxBodySize {
+		return dst, fmt.Errorf("contents of %q are %d byte(s), more than the max of %d byte(s): %w", url, len(entry.Body), o.maxBodySize, ErrBodyTooLarge)
+	}
+
+	if o.resolvedURL != nil {
+		*o.resolvedURL = url
+	}
+
+	return append(dst, entry.Body...), nil
+}
+
+// cacheExpiry returns the time after which contents served with header are stale, based on its Cache-Control header.
+// Contents served without a max-age directive are stale straight away. It reports false should the contents not be
+// allowed to be stored at all.
+func cacheExpiry(header *fasthttp.ResponseHeader, now time.Time) (time.Time, bool) {
+	expires := now
+	noCache := false
+
+	for _, directive := range bytes.Split(header.Peek("Cache-Control"), []byte(",")) {
+		directive = bytes.ToLower(bytes.TrimSpace(directive))
+
+		switch {
+		case bytes.Equal(directive, []byte("no-store")):
+			return now, false
+		case bytes.Equal(directive, []byte("no-cache")):
+			noCache = true
+		case bytes.HasPrefix(directive, []byte("max-age=")):
+			seconds, err := strconv.ParseUint(string(bytes.Trim(directive[len("max-age="):], `"`)), 10, 32)
+			if err == nil {
+				expires = now.Add(time.Duration(seconds) * time.Second)
+			}
+		}
+	}
+
+	if noCache {
+		return now, true
+	}
+
+	return expires, true
+}
@@ -0,0 +1,145 @@
+package nicehttp
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+)
+
+var _ Writer = (*hashingWriter)(nil)
+
+// VerifyChecksum has a file download verify that the digest of its contents computed using hash function algo is
+// want. The download fails with a *ChecksumError should the digest not match.
+//
+// Serial downloads have their digest computed as their contents are written. Chunks downloaded in parallel may arrive
+// out of order however, so parallel downloads instead have their contents read back once they have completed in order
+// to compute their digest.
+func VerifyChecksum(algo crypto.Hash, want []byte) DownloadOption {
+	return func(o *downloadOptions) {
+		o.checksumHash = algo
+		o.checksumWant = want
+	}
+}
+
+// ComputeChecksum has a file download compute the digest of its contents using hash function algo, and store it into
+// result once the download completes. Should VerifyChecksum also be provided, result additionally reports whether or
+// not the digest matched, and is filled in even should the download fail with a *ChecksumError, such that the digest
+// actually computed may be logged. algo takes precedence over the hash function provided to VerifyChecksum should it
+// be provided after it.
+//
+// Like VerifyChecksum, serial downloads have their digest computed as their contents are written, while parallel
+// downloads have their contents read back once they have completed.
+func ComputeChecksum(algo crypto.Hash, result *ChecksumResult) DownloadOption {
+	return func(o *downloadOptions) {
+		o.checksumHash = algo
+		o.checksumResult = result
+	}
+}
+
+// ChecksumResult is the digest of the contents of a download, computed by providing ComputeChecksum to a file
+// download.
+type ChecksumResult struct {
+	// Hash function the digest was computed with.
+	Hash crypto.Hash
+
+	// Digest of the contents of the download.
+	Sum []byte
+
+	// Whether or not the digest was compared against one provided to VerifyChecksum.
+	Verified bool
+
+	// Whether or not the digest matched the one provided to VerifyChecksum. Always true should Verified be false.
+	Match bool
+}
+
+// newChecksumHash instantiates the hash function to verify the checksum of a download with. It returns nil should
+// no checksum be verified.
+func (o downloadOptions) newChecksumHash() (hash.Hash, error) {
+	if o.checksumHash == 0 {
+		return nil, nil
+	}
+
+	if !o.checksumHash.Available() {
+		return nil, fmt.Errorf("hash function %d is not linked into the binary", o.checksumHash)
+	}
+
+	return o.checksumHash.New(), nil
+}
+
+// ChecksumError is returned should the digest of the contents of a download not match the digest expected.
+type ChecksumError struct {
+	Hash crypto.Hash
+	Want []byte
+	Got  []byte
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("checksum mismatch: expected %x, got %x", e.Want, e.Got)
+}
+
+// hashingWriter implements Writer, writing all bytes written using Write into a hash as well.
+type hashingWriter struct {
+	Writer
+	h hash.Hash
+}
+
+// Write implements io.Writer.
+func (w *hashingWriter) Write(b []byte) (int, error) {
+	n, err := w.Writer.Write(b)
+	w.h.Write(b[:n])
+	return n, err
+}
+
+// verifyChecksum checks that the digest computed by h matches the one expected in o, should o expect any. The digest
+// is reported into the ChecksumResult of o, should o have one.
+func (o downloadOptions) verifyChecksum(h hash.Hash) error {
+	got := h.Sum(nil)
+	match := o.checksumWant == nil || string(got) == string(o.checksumWant)
+
+	if o.checksumResult != nil {
+		*o.checksumResult = ChecksumResult{Hash: o.checksumHash, Sum: got, Verified: o.checksumWant != nil, Match: match}
+	}
+
+	if !match {
+		return &ChecksumError{Hash: o.checksumHash, Want: o.checksumWant, Got: got}
+	}
+
+	return nil
+}
+
+// hashFrom writes the first n bytes read from r into h.
+func hashFrom(h hash.Hash, r io.ReaderAt, n int64) error {
+	_, err := io.Copy(h, io.NewSectionReader(r, 0, n))
+	return err
+}
+
+// DownloadFileHashed serially downloads the contents of url, and writes its contents to both a newly-created file
+// titled filename and h. It returns the digest computed by h. As chunks downloaded in parallel may arrive out of
+// order, it returns an error should c.AcceptsRanges be set. Like DownloadFile, the contents are written to a temporary
+// file which is only renamed to filename once the download has completed.
+func (c *Client) DownloadFileHashed(filename, url string, h hash.Hash) ([]byte, error) {
+	return c.DownloadFileHashedDeadline(filename, url, h, zeroTime)
+}
+
+// DownloadFileHashedTimeout serially downloads the contents of url, and writes its contents to both a newly-created
+// file titled filename and h. It returns the digest computed by h.
+func (c *Client) DownloadFileHashedTimeout(filename, url string, h hash.Hash, timeout time.Duration) ([]byte, error) {
+	return c.DownloadFileHashedDeadline(filename, url, h, c.clock().Add(timeout))
+}
+
+// DownloadFileHashedDeadline serially downloads the contents of url, and writes its contents to both a newly-created
+// file titled filename and h. It returns the digest computed by h.
+func (c *Client) DownloadFileHashedDeadline(filename, url string, h hash.Hash, deadline time.Time) ([]byte, error) {
+	if c.AcceptsRanges {
+		return nil, errors.New("contents may only be hashed as they are downloaded serially - disable AcceptsRanges")
+	}
+
+	if err := c.downloadFile(filename, url, 0, false, deadline, downloadOptions{hash: h}); err != nil {
+		return nil, err
+	}
+
+	return h.Sum(nil), nil
+}
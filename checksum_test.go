@@ -0,0 +1,137 @@
+package nicehttp
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownloadFileHashed(t *testing.T) {
+	contents := testContents(64)
+
+	var log requestLog
+
+	srv := newContentServer(t, contents, &log)
+
+	dir, err := ioutil.TempDir("", "nicehttp")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := newTestClient(8)
+	c.TempDir = dir
+
+	filename := filepath.Join(dir, "file")
+
+	// Chunks downloaded in parallel may arrive out of order, such that contents may not be hashed as they are
+	// written should the client download in chunks.
+
+	if _, err := c.DownloadFileHashed(filename, srv.URL, sha256.New()); err == nil {
+		t.Fatalf("expected hashing a download in chunks to fail")
+	}
+	if n := len(log.all()); n != 0 {
+		t.Fatalf("expected no requests to be sent, got %d", n)
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Fatalf("expected no file to be created, got %v", err)
+	}
+
+	c.AcceptsRanges = false
+
+	sum, err := c.DownloadFileHashed(filename, srv.URL, sha256.New())
+	if err != nil {
+		t.Fatalf("failed to download: %s", err)
+	}
+
+	if want := sha256.Sum256(contents); !bytes.Equal(sum, want[:]) {
+		t.Fatalf("expected digest %x, got %x", want, sum)
+	}
+
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("failed to read downloaded file: %s", err)
+	}
+	if !bytes.Equal(buf, contents) {
+		t.Fatalf("downloaded contents do not match")
+	}
+
+	for _, req := range log.all() {
+		if req.Range != "" {
+			t.Fatalf("expected contents to be downloaded serially, got a request for range %q", req.Range)
+		}
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	c.CheckStatus = true
+
+	if _, err := c.DownloadFileHashed(filepath.Join(dir, "failed"), failing.URL, sha256.New()); err == nil {
+		t.Fatalf("expected download to fail")
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read temp dir: %s", err)
+	}
+	if len(files) != 1 || files[0].Name() != "file" {
+		t.Fatalf("expected no files to be left behind by a failed download, got %d file(s)", len(files))
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	contents := testContents(64)
+
+	srv := newContentServer(t, contents, nil)
+
+	dir, err := ioutil.TempDir("", "nicehttp")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	want := sha256.Sum256(contents)
+
+	// Serial downloads hash contents as they are written, while chunked downloads read them back once complete.
+
+	for _, acceptsRanges := range []bool{false, true} {
+		c := newTestClient(8)
+		c.AcceptsRanges = acceptsRanges
+
+		filename := filepath.Join(dir, "file")
+
+		var result ChecksumResult
+
+		if err := c.DownloadFile(filename, srv.URL, VerifyChecksum(crypto.SHA256, want[:]), ComputeChecksum(crypto.SHA256, &result)); err != nil {
+			t.Fatalf("failed to download with AcceptsRanges=%t: %s", acceptsRanges, err)
+		}
+
+		if !result.Verified || !result.Match || !bytes.Equal(result.Sum, want[:]) {
+			t.Fatalf("expected digest %x to be verified with AcceptsRanges=%t, got %+v", want, acceptsRanges, result)
+		}
+
+		mismatch := append([]byte(nil), want[:]...)
+		mismatch[0] ^= 0xff
+
+		err := c.DownloadFile(filepath.Join(dir, "mismatch"), srv.URL, VerifyChecksum(crypto.SHA256, mismatch))
+
+		var checksumErr *ChecksumError
+		if !errors.As(err, &checksumErr) || !bytes.Equal(checksumErr.Got, want[:]) {
+			t.Fatalf("expected a *ChecksumError with AcceptsRanges=%t, got %v", acceptsRanges, err)
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, "mismatch")); !os.IsNotExist(err) {
+			t.Fatalf("expected a file failing verification to not be created, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,456 @@
+package nicehttp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/sync/errgroup"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// downloadInChunks downloads the contents of urls comprised of length bytes in chunks using multiple workers, and
+// stores it in writer w. All of urls are expected to serve the exact same contents. Each worker downloads chunks from
+// a different URL, and fails over to the other URLs should downloading a chunk fail.
+//
+// Chunks which t reports to already have been downloaded are skipped, and t is notified every time a chunk has been
+// downloaded. t may be nil.
+func (c *Client) downloadInChunks(f io.WriterAt, urls []string, length int, deadline time.Time, t chunkTracker) error {
+	var ranges []ByteRange
+
+	for _, r := range c.scheduler().Schedule(length, c.chunkSize(length)) {
+		if t == nil || !t.completed(r.Start, r.End) {
+			ranges = append(ranges, r)
+		}
+	}
+
+	return c.downloadRanges(f, urls, ranges, length, deadline, t)
+}
+
+// downloadRanges downloads byte ranges of the contents of urls comprised of length bytes using multiple workers, and
+// stores them in writer w. It fails with a *ChunkError listing which of ranges were and were not downloaded should
+// downloading any of ranges fail. Byte ranges may only be split up into smaller ones should t be nil.
+func (c *Client) downloadRanges(f io.WriterAt, urls []string, ranges []ByteRange, length int, deadline time.Time, t chunkTracker) (err error) {
+	c, done := c.trackStats(urls[0])
+	defer func() { done(err) }()
+
+	c.tookPath(true)
+
+	// A deadline that has already passed, i.e. as a result of a slow request for the headers of urls, fails the
+	// download straight away rather than have workers be spawned only to time out.
+
+	if c.deadlinePassed(deadline) {
+		return fasthttp.ErrTimeout
+	}
+
+	if c.PinToResolvedIP {
+		instance, err := c.pinToResolvedIP(urls, deadline)
+		if err != nil {
+			return fmt.Errorf("failed to pin %q to a resolved ip: %w", urls[0], err)
+		}
+
+		pinned := *c
+		pinned.Instance = instance
+
+		c = &pinned
+	}
+
+	// Have the context of all workers be canceled once the deadline passes or once the download is canceled, such
+	// that workers that are waiting to send a request or to write a chunk return promptly.
+
+	ctx, cancel := c.cancelable(context.Background())
+	defer cancel()
+
+	// Should the deadline be split across chunks, it bounds each request for a chunk rather than the download as a
+	// whole.
+
+	chunkDeadline := deadline
+	if c.PerChunkDeadline {
+		deadline = zeroTime
+	}
+
+	if !deadline.IsZero() {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	// Wake up workers blocked on writing to f should the download fail. ctx is always canceled once all workers
+	// have returned.
+
+	if a, ok := f.(aborter); ok {
+		go func() {
+			<-ctx.Done()
+			a.abort(ctx.Err())
+		}()
+	}
+
+	var written int64
+
+	f = c.throttleWriterAt(f, deadline)
+
+	numWorkers := c.NumWorkers
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+
+	// Fill up a pool with byte ranges to be downloaded from urls, which workers claim byte ranges from. Byte ranges
+	// may only be split up should their completion not be tracked.
+
+	var expected int64
+	for _, r := range ranges {
+		expected += int64(r.End - r.Start)
+	}
+
+	pool := newRangePool(ranges, numWorkers, t == nil)
+
+	share := newDeadlineShare(chunkDeadline, expected, numWorkers, c.clock)
+
+	var (
+		mu        sync.Mutex
+		completed []ByteRange
+	)
+
+	resolved := newResolvedURLs(urls)
+
+	// Workers send requests for chunks straight to the URLs that urls redirected to. Requests to any of them must not
+	// carry credentials meant for the host of the URL that redirected to it.
+
+	origins := make([]*Client, len(urls))
+	for k, url := range urls {
+		origins[k] = c.withOrigin(url)
+	}
+
+	var inflight chan struct{}
+	if c.MaxInFlightChunks > 0 {
+		inflight = make(chan struct{}, c.MaxInFlightChunks)
+	}
+
+	// Spawn w workers that will claim, dispatch and execute byte range HTTP requests.
+
+	for i := 0; i < numWorkers; i++ {
+		i := i
+
+		work := func(req *fasthttp.Request, res *fasthttp.Response) error {
+			for {
+				if c.deadlinePassed(deadline) {
+					return fmt.Errorf("worker %d ran out of time: %w", i, fasthttp.ErrTimeout)
+				}
+
+				if err := contextError(ctx); err != nil {
+					return err
+				}
+
+				r, ok := pool.next()
+				if !ok {
+					return nil
+				}
+
+				// Start off downloading from the URL assigned to this worker, and fail over to the next URL
+				// should downloading the byte range fail.
+
+				var (
+					n   int
+					err error
+				)
+
+				deadline := deadline
+				if c.PerChunkDeadline {
+					deadline = share.next(r, expected-atomic.LoadInt64(&written))
+				}
+
+				for j := 0; j < len(urls); j++ {
+					k := (i + j) % len(urls)
+					url := resolved.get(k)
+
+					if n, err = origins[k].downloadChunk(ctx, req, res, f, url, r, deadline, inflight); err == nil {
+						resolved.set(k, req.URI().String())
+						break
+					}
+
+					resolved.reset(k)
+
+					err = fmt.Errorf("worker %d failed to get bytes range (start: %d, end: %d) from %q: %w", i, r.Start, r.End, url, err)
+
+					if ctx.Err() != nil {
+						break
+					}
+				}
+
+				if err != nil {
+					return err
+				}
+
+				if t != nil {
+					if err := t.complete(r.Start, r.End); err != nil {
+						return fmt.Errorf("worker %d failed to record bytes range (start: %d, end: %d) as downloaded: %w", i, r.Start, r.End, err)
+					}
+				}
+
+				c.wrote(int64(n))
+
+				if c.stats != nil {
+					atomic.AddInt64(&c.stats.chunks, 1)
+				}
+
+				mu.Lock()
+				completed = append(completed, r)
+				mu.Unlock()
+
+				c.event(ChunkDone{Start: r.Start, End: r.End})
+				c.event(Progress{Done: atomic.AddInt64(&written, int64(n)), Total: int64(length)})
+			}
+		}
+
+		g.Go(func() error {
+			return c.runWorker(ctx, work)
+		})
+	}
+
+	// Wait until all byte ranges have been downloaded, or return early if an error was encountered downloading
+	// a chunk.
+
+	if err := g.Wait(); err != nil {
+		if c.wasCanceled() {
+			err = ErrCanceled
+		}
+
+		return &ChunkError{
+			URL:       urls[0],
+			Err:       err,
+			Completed: completed,
+			Failed:    subtractRanges(ranges, completed),
+		}
+	}
+
+	if written != expected {
+		return fmt.Errorf("failed to download %q in chunks: got %d byte(s), expected %d: %w", urls[0], written, expected, ErrShortDownload)
+	}
+
+	return nil
+}
+
+// deadlineShare splits the time left until a deadline across the chunks of a download that are left to be downloaded.
+type deadlineShare struct {
+	deadline   time.Time
+	budget     time.Duration
+	expected   int64
+	numWorkers int
+	now        func() time.Time
+}
+
+// newDeadlineShare splits the time left until deadline across expected bytes that are to be downloaded by numWorkers
+// workers, reading the current time from now.
+func newDeadlineShare(deadline time.Time, expected int64, numWorkers int, now func() time.Time) *deadlineShare {
+	return &deadlineShare{
+		deadline:   deadline,
+		budget:     deadline.Sub(now()),
+		expected:   expected,
+		numWorkers: numWorkers,
+		now:        now,
+	}
+}
+
+// next returns the deadline of a request for r, given that remaining bytes are left to be downloaded, r included. The
+// time left until the deadline is split across the number of rounds of requests the workers have yet to make. r is
+// given at least the share of time it would have been given had the time left been split across all chunks up front,
+// such that chunks that are requested once the deadline has passed still get a fair slice of time.
+func (s *deadlineShare) next(r ByteRange, remaining int64) time.Time {
+	if s.deadline.IsZero() {
+		return zeroTime
+	}
+
+	now := s.now()
+
+	share := time.Duration(float64(s.deadline.Sub(now)) / rounds(r, remaining, s.numWorkers))
+
+	if min := time.Duration(float64(s.budget) / rounds(r, s.expected, s.numWorkers)); share < min {
+		share = min
+	}
+
+	return now.Add(share)
+}
+
+// rounds returns the number of rounds of requests for chunks as large as r that numWorkers workers have to make to
+// download remaining bytes. It is never less than one.
+func rounds(r ByteRange, remaining int64, numWorkers int) float64 {
+	n := float64(remaining) / (float64(r.End-r.Start) * float64(numWorkers))
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+// runWorker runs work using a request and response that are either acquired for the duration of work, or held by a
+// goroutine of c.Pool should c.Pool be set.
+func (c *Client) runWorker(ctx context.Context, work func(req *fasthttp.Request, res *fasthttp.Response) error) error {
+	if c.Pool != nil {
+		return c.Pool.run(ctx, work)
+	}
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	res := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(res)
+
+	return work(req, res)
+}
+
+// resolvedURLs keeps track of the URLs that a set of URLs were last redirected to, such that the redirects of each URL
+// are only followed once rather than once per chunk.
+type resolvedURLs struct {
+	mu       sync.Mutex
+	urls     []string
+	resolved []string
+}
+
+func newResolvedURLs(urls []string) *resolvedURLs {
+	return &resolvedURLs{urls: urls, resolved: append([]string(nil), urls...)}
+}
+
+// get returns the URL that the i-th URL was last redirected to, or the i-th URL itself should it not be known yet.
+func (r *resolvedURLs) get(i int) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.resolved[i]
+}
+
+// set records that the i-th URL was redirected to url.
+func (r *resolvedURLs) set(i int, url string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.resolved[i] = url
+}
+
+// reset forgets the URL that the i-th URL was redirected to, such that its redirects are followed once more should
+// the URL it was redirected to stop working.
+func (r *resolvedURLs) reset(i int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.resolved[i] = r.urls[i]
+}
+
+// ChunkError is returned should downloading any byte range of a download in chunks fail. It lists which byte ranges
+// were downloaded and written before the download failed, and which were not, such that only the byte ranges that
+// failed may be downloaded again using DownloadRanges into the same io.WriterAt.
+type ChunkError struct {
+	// URL whose contents were being downloaded.
+	URL string
+
+	// Error that caused the download to fail, which may be a timeout.
+	Err error
+
+	// Byte ranges that were downloaded and written, in the order they completed.
+	Completed []ByteRange
+
+	// Byte ranges that were not downloaded, or not written in full, ordered by their start.
+	Failed []ByteRange
+}
+
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("failed to download %q in chunks: %s", e.URL, e.Err)
+}
+
+func (e *ChunkError) Unwrap() error {
+	return e.Err
+}
+
+// downloadChunk downloads byte range r of the contents of url using req and res, and writes it to f at the offset
+// of r. It returns the number of bytes written to f. At most cap(inflight) requests are in flight at once should
+// inflight not be nil.
+func (c *Client) downloadChunk(ctx context.Context, req *fasthttp.Request, res *fasthttp.Response, f io.WriterAt, url string, r ByteRange, deadline time.Time, inflight chan struct{}) (int, error) {
+	req.Reset()
+	req.SetRequestURI(url)
+	req.Header.SetByteRange(r.Start, r.End-1)
+
+	if c.ifRange != "" {
+		req.Header.Set("If-Range", c.ifRange)
+	}
+
+	if inflight != nil {
+		select {
+		case inflight <- struct{}{}:
+		case <-ctx.Done():
+			return 0, contextError(ctx)
+		}
+	}
+
+	err := c.DoDeadline(req, res, deadline)
+
+	if inflight != nil {
+		<-inflight
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if res.StatusCode() == fasthttp.StatusNotModified {
+		return 0, ErrNotModified
+	}
+
+	if err := c.checkStatus(res); err != nil {
+		return 0, err
+	}
+
+	// Servers that ignore the Range header, or whose contents no longer match the If-Range header, respond with the
+	// entirety of the contents of url, which must not be written at the offset of the byte range requested. Contents
+	// that still report the validator the If-Range header carries have merely had their byte range ignored.
+
+	if res.StatusCode() == fasthttp.StatusOK {
+		if c.ifRange != "" && string(res.Header.Peek("ETag")) != c.ifRange && string(res.Header.Peek("Last-Modified")) != c.ifRange {
+			return 0, ErrContentChanged
+		}
+		return 0, ErrRangeIgnored
+	}
+
+	if res.StatusCode() != fasthttp.StatusPartialContent {
+		return 0, fmt.Errorf("unexpected status code %d", res.StatusCode())
+	}
+
+	// Byte ranges of contents that have a content encoding applied to them are byte ranges of the encoded contents,
+	// which must not be written at the offset of the byte range requested.
+
+	if enc := res.Header.Peek("Content-Encoding"); len(enc) > 0 && !bytes.EqualFold(enc, []byte("identity")) {
+		return 0, fmt.Errorf("got Content-Encoding %q: %w", enc, ErrRangeEncoded)
+	}
+
+	// Responses for a byte range other than the one requested must not be written at the offset of the byte range
+	// requested, lest the bytes be silently misplaced.
+
+	if start, end, _, ok := parseContentRange(res.Header.Peek("Content-Range")); !ok || start != r.Start || end != r.End {
+		return 0, fmt.Errorf("requested bytes %d-%d, got Content-Range %q: %w", r.Start, r.End-1, res.Header.Peek("Content-Range"), ErrRangeMismatch)
+	}
+
+	// Servers that close the connection early may respond with less than the entirety of the byte range requested.
+
+	if n := len(res.Body()); n != r.End-r.Start {
+		return 0, fmt.Errorf("got %d byte(s), expected %d: %w", n, r.End-r.Start, ErrShortDownload)
+	}
+
+	if err := res.BodyWriteTo(NewWriterAtOffset(f, int64(r.Start))); err != nil {
+		return 0, fmt.Errorf("failed to write to file at offset %d: %w", r.Start, err)
+	}
+
+	return len(res.Body()), nil
+}
+
+// contextError returns the error of ctx, having it be fasthttp.ErrTimeout should the deadline of ctx have passed.
+func contextError(ctx context.Context) error {
+	err := ctx.Err()
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fasthttp.ErrTimeout
+	}
+	return err
+}
@@ -0,0 +1,220 @@
+package nicehttp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestChunkWorkersDetectIgnoredRange(t *testing.T) {
+	contents := testContents(64)
+
+	var ranged int32
+
+	// Respond to every request with the entirety of the contents, regardless of the byte range requested.
+
+	c := WrapClient(transportFunc(func(req *fasthttp.Request, res *fasthttp.Response) error {
+		if len(req.Header.Peek("Range")) > 0 {
+			atomic.AddInt32(&ranged, 1)
+		}
+
+		res.SetStatusCode(fasthttp.StatusOK)
+		res.SetBody(contents)
+		return nil
+	}))
+	c.NumWorkers = 2
+	c.ChunkSize = 8
+
+	buf := NewWriteBuffer(make([]byte, len(contents)))
+
+	if err := c.DownloadInChunks(buf, "http://example.com/file", len(contents)); !errors.Is(err, ErrRangeIgnored) {
+		t.Fatalf("expected ErrRangeIgnored, got %v", err)
+	}
+
+	// Downloads that may fall back to downloading serially do so instead of failing.
+
+	buf = NewWriteBuffer(nil)
+
+	if err := c.Download(buf, "http://example.com/file", len(contents), true); err != nil {
+		t.Fatalf("failed to download: %s", err)
+	}
+	if !bytes.Equal(buf.Bytes(), contents) {
+		t.Fatalf("downloaded contents do not match")
+	}
+
+	if atomic.LoadInt32(&ranged) == 0 {
+		t.Fatalf("expected byte ranges to be requested")
+	}
+}
+
+// stalledTransport is a Transport which responds to requests for the first chunk of contents, and stalls on every
+// other request until its deadline passes, the same way fasthttp times out requests to a stalled server.
+type stalledTransport struct {
+	contents []byte
+}
+
+func (t stalledTransport) Do(req *fasthttp.Request, res *fasthttp.Response) error {
+	return t.DoDeadline(req, res, zeroTime)
+}
+
+func (t stalledTransport) DoTimeout(req *fasthttp.Request, res *fasthttp.Response, timeout time.Duration) error {
+	return t.DoDeadline(req, res, time.Now().Add(timeout))
+}
+
+func (t stalledTransport) DoDeadline(req *fasthttp.Request, res *fasthttp.Response, deadline time.Time) error {
+	if string(req.Header.Peek("Range")) == "bytes=0-7" {
+		res.SetStatusCode(fasthttp.StatusPartialContent)
+		res.Header.Set("Content-Range", fmt.Sprintf("bytes 0-7/%d", len(t.contents)))
+		res.SetBody(t.contents[:8])
+		return nil
+	}
+
+	if deadline.IsZero() {
+		deadline = time.Now().Add(10 * time.Second)
+	}
+
+	time.Sleep(time.Until(deadline))
+
+	return fasthttp.ErrTimeout
+}
+
+func TestDeadlineStopsInFlightWorkers(t *testing.T) {
+	contents := testContents(64)
+
+	c := WrapClient(stalledTransport{contents: contents})
+	c.NumWorkers = 2
+	c.ChunkSize = 8
+
+	start := time.Now()
+
+	buf := NewWriteBuffer(make([]byte, len(contents)))
+	err := c.DownloadInChunksTimeout(buf, "http://example.com/file", len(contents), 200*time.Millisecond)
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("expected download to return near its deadline, took %s", elapsed)
+	}
+
+	if !errors.Is(err, fasthttp.ErrTimeout) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+}
+
+func TestChunkWorkersDetectContentRangeMismatch(t *testing.T) {
+	contents := testContents(64)
+
+	// Respond to every request for a byte range with the byte range that follows it.
+
+	c := WrapClient(transportFunc(func(req *fasthttp.Request, res *fasthttp.Response) error {
+		var start, end int
+		if _, err := fmt.Sscanf(string(req.Header.Peek("Range")), "bytes=%d-%d", &start, &end); err != nil {
+			return err
+		}
+
+		start, end = (start+8)%len(contents), (end+8)%len(contents)
+
+		res.SetStatusCode(fasthttp.StatusPartialContent)
+		res.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(contents)))
+		res.SetBody(contents[start : end+1])
+		return nil
+	}))
+	c.NumWorkers = 2
+	c.ChunkSize = 8
+
+	buf := NewWriteBuffer(make([]byte, len(contents)))
+
+	if err := c.DownloadInChunks(buf, "http://example.com/file", len(contents)); !errors.Is(err, ErrRangeMismatch) {
+		t.Fatalf("expected ErrRangeMismatch, got %v", err)
+	}
+}
+
+func TestChunkWorkersDetectEncodedRanges(t *testing.T) {
+	contents := testContents(64)
+
+	var encoded bytes.Buffer
+
+	zw := gzip.NewWriter(&encoded)
+	if _, err := zw.Write(contents); err != nil {
+		t.Fatalf("failed to compress contents: %s", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to compress contents: %s", err)
+	}
+
+	// Serve byte ranges of the compressed contents rather than of the contents themselves.
+
+	c := WrapClient(transportFunc(func(req *fasthttp.Request, res *fasthttp.Response) error {
+		res.Header.Set("Content-Encoding", "gzip")
+
+		var start, end int
+		if _, err := fmt.Sscanf(string(req.Header.Peek("Range")), "bytes=%d-%d", &start, &end); err != nil {
+			res.SetStatusCode(fasthttp.StatusOK)
+			res.SetBody(encoded.Bytes())
+			return nil
+		}
+
+		if end >= encoded.Len() {
+			end = encoded.Len() - 1
+		}
+
+		res.SetStatusCode(fasthttp.StatusPartialContent)
+		res.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, encoded.Len()))
+		res.SetBody(encoded.Bytes()[start : end+1])
+		return nil
+	}))
+	c.NumWorkers = 2
+	c.ChunkSize = 8
+
+	buf := NewWriteBuffer(make([]byte, len(contents)))
+
+	if err := c.DownloadInChunks(buf, "http://example.com/file", len(contents)); !errors.Is(err, ErrRangeEncoded) {
+		t.Fatalf("expected ErrRangeEncoded, got %v", err)
+	}
+
+	// Downloads that may fall back to downloading serially do so, and decompress the contents as they are written.
+
+	buf = NewWriteBuffer(nil)
+
+	if err := c.Download(buf, "http://example.com/file", len(contents), true); err != nil {
+		t.Fatalf("failed to download: %s", err)
+	}
+	if !bytes.Equal(buf.Bytes(), contents) {
+		t.Fatalf("downloaded contents do not match")
+	}
+}
+
+func TestPassedDeadline(t *testing.T) {
+	contents := testContents(64)
+
+	var calls int32
+
+	c := WrapClient(transportFunc(func(req *fasthttp.Request, res *fasthttp.Response) error {
+		atomic.AddInt32(&calls, 1)
+
+		res.SetStatusCode(fasthttp.StatusOK)
+		res.SetBody(contents)
+		return nil
+	}))
+	c.NumWorkers = 2
+	c.ChunkSize = 8
+
+	deadline := time.Now().Add(-time.Second)
+
+	buf := NewWriteBuffer(make([]byte, len(contents)))
+
+	if err := c.DownloadInChunksDeadline(buf, "http://example.com/file", len(contents), deadline); !errors.Is(err, fasthttp.ErrTimeout) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+
+	if _, err := c.DownloadBytesDeadline(nil, "http://example.com/file", deadline); !errors.Is(err, fasthttp.ErrTimeout) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+
+	if n := atomic.LoadInt32(&calls); n != 0 {
+		t.Fatalf("expected no requests to be sent past the deadline, got %d", n)
+	}
+}
@@ -1,20 +1,68 @@
 package nicehttp
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"github.com/lithdew/bytesutil"
 	"github.com/valyala/fasthttp"
-	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
 	"io"
+	"net/http"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 )
 
 // zeroTime is the zero-value of time.Time.
 var zeroTime time.Time
 
+// deadlinePassed reports whether or not deadline is set, and has already passed.
+func (c *Client) deadlinePassed(deadline time.Time) bool {
+	return !deadline.IsZero() && !c.clock().Before(deadline)
+}
+
+// ErrUseLastResponse may be returned by Client.CheckRedirect to stop following redirects, and have the redirect
+// response be returned as-is.
+var ErrUseLastResponse = errors.New("use last response")
+
+// ErrRangeIgnored is returned should a server respond to a request for a byte range with its entire contents.
+var ErrRangeIgnored = errors.New("server ignored the requested byte range")
+
+// ErrRangeEncoded is returned should a server respond to a request for a byte range with a body that has a content
+// encoding such as gzip applied to it. The byte range is then one of the encoded contents rather than one of the
+// contents, such that it may not be written at the offset of the byte range requested. Contents whose byte ranges are
+// encoded are to be downloaded serially instead, which downloads that would otherwise be chunked fall back to.
+var ErrRangeEncoded = errors.New("server applied a content encoding to the requested byte range - download serially instead")
+
+// ErrRangeMismatch is returned should a server respond to a request for a byte range with a Content-Range header that
+// does not match the byte range requested, i.e. as a result of a misbehaving caching proxy.
+var ErrRangeMismatch = errors.New("server responded with a different byte range than requested")
+
+// ErrContentChanged is returned should the contents of a URL change while it is being downloaded in chunks. Requests
+// for chunks carry an If-Range header with the ETag or Last-Modified date the URL reported before it started being
+// downloaded, such that a server whose contents have since changed responds with its entire contents rather than
+// with a byte range of a different version of its contents, which would otherwise be silently stitched together with
+// the chunks already downloaded.
+var ErrContentChanged = errors.New("contents changed while being downloaded")
+
+// ErrEgressBudgetExceeded is returned once the total number of bytes transferred by a Client exceeds its
+// MaxEgressBytes.
+var ErrEgressBudgetExceeded = errors.New("egress budget exceeded")
+
+// ErrHeadTimeout is returned should the request made to query the headers of a URL before downloading it take longer
+// than Client.HeadTimeout.
+var ErrHeadTimeout = errors.New("timed out querying headers")
+
+// ErrShortDownload is returned should the number of bytes written by a download not match the content length that
+// was reported for its contents.
+var ErrShortDownload = errors.New("number of bytes downloaded does not match content length")
+
 // Transport represents the interface of a HTTP client supported by nicehttp.
 type Transport interface {
 	Do(req *fasthttp.Request, res *fasthttp.Response) error
@@ -36,8 +84,250 @@ type Client struct {
 	// Size of individual byte chunks downloaded.
 	ChunkSize int
 
-	// Max number of redirects to follow before a request is marked to have failed.
+	// Decide whether or not the size of chunks is picked based on the content length of a download, overriding
+	// ChunkSize. The content length is split evenly across NumWorkers chunks, with each chunk being no smaller than
+	// 1 MiB and no larger than 64 MiB, rounded up to a multiple of 64 KiB. The ceiling bounds the memory each worker
+	// buffers a chunk into, such that large downloads are split into more chunks than there are workers instead.
+	AutoChunkSize bool
+
+	// Decides which byte ranges are downloaded, and in which order. Defaults to SequentialScheduler should it be nil.
+	Scheduler Scheduler
+
+	// Decide whether or not the deadline of a chunked download is split across its chunks rather than bound the
+	// download as a whole. Each request for a chunk is given a deadline of its own, which is the time left until the
+	// deadline of the download divided by the number of rounds of requests the workers have yet to make, but no less
+	// than the share of time it would have been given had the time been split evenly across all chunks up front.
+	//
+	// A download whose chunks are each downloaded within their share of time thus completes even should it overrun its
+	// deadline, and a couple of slow chunks do not necessarily doom the download, as chunks that follow them are given
+	// a fair slice of time regardless. The tradeoff is that the deadline of the download is no longer a hard bound on
+	// how long it takes. Defaults to false, where the deadline bounds every request of the download, and the download
+	// fails as a whole once it passes.
+	PerChunkDeadline bool
+
+	// Max number of requests for byte ranges that may be in flight at once per download, regardless of NumWorkers.
+	// NumWorkers determines how many chunks are being worked on at once, while MaxInFlightChunks caps how many of
+	// those workers may be waiting on a response at once. Zero means that up to NumWorkers requests may be in flight.
+	MaxInFlightChunks int
+
+	// Semaphore that every request acquires a unit of for as long as it is in flight, which may be shared across
+	// multiple clients such that the number of requests in flight across all of them at once is bounded, i.e. to
+	// enforce a process-wide connection budget. Both requests for chunks and serial requests acquire it, while
+	// retries release it whilst they back off. No limit is enforced should it be nil.
+	Semaphore *semaphore.Weighted
+
+	// Max number of chunks buffered in memory by DownloadStream and OpenStream while waiting for the chunks before
+	// them to be downloaded. Defaults to NumWorkers should it be zero.
+	MaxBufferedChunks int
+
+	// Long-lived pool of goroutines that chunks are downloaded on, shared across downloads. Each download spawns its
+	// own short-lived workers should it be nil.
+	Pool *WorkerPool
+
+	// Max number of files downloaded at once by DownloadFiles.
+	MaxConcurrentDownloads int
+
+	// Decide whether or not all workers downloading chunks of a URL dial the same IP address, which is resolved once
+	// before the download starts. The next resolved IP address is dialed should said address refuse connections.
+	// Connections to a pinned IP address are kept alive across downloads. It only takes effect should Instance be a
+	// *fasthttp.Client.
+	PinToResolvedIP bool
+
+	// Decide whether or not disk space for files is reserved up front before they are downloaded, such that running
+	// out of disk space is reported immediately. Only supported on Linux; files are otherwise truncated to size.
+	PreallocateDisk bool
+
+	// Decide whether or not the temporary file a failed DownloadFile call was writing to is kept around for debugging,
+	// rather than removed.
+	KeepPartialFiles bool
+
+	// Directory that DownloadFile stages the temporary file it downloads into in before moving it to its destination,
+	// i.e. to stage downloads on a fast local disk before moving them onto a slow network mount. Should the directory
+	// be on a different filesystem than the destination, the temporary file is copied into another temporary file
+	// next to the destination, which is then atomically renamed to the destination. Defaults to the directory of the
+	// destination should it be empty.
+	TempDir string
+
+	// Decide whether or not file downloads are skipped, failing with ErrAlreadyDownloaded, should the file to be
+	// downloaded into already exist and match the contents of its URL. A file matches should its size equal the
+	// content length reported by the URL. Should the URL also report an ETag, and should a sidecar file named after
+	// the file suffixed with ".etag" exist, the ETag stored in the sidecar file must equal it as well. The sidecar file
+	// is written once a download completes, should its URL have reported an ETag. Files whose URL does not report a
+	// content length are always downloaded.
+	SkipIfPresent bool
+
+	// Decide whether or not files are not synced to disk once they have been downloaded. Files are synced by default
+	// such that a crash right after a download completes does not leave behind a truncated file. Skipping the sync
+	// trades durability for speed.
+	SkipSync bool
+
+	// Value of the Accept-Encoding header set on every request, unless a request already has the header set.
+	// Defaults to "identity". Advertising any other encoding, such as "gzip, br", disables downloading in parallel
+	// chunks, as byte ranges of encoded contents do not map onto the decoded contents. Contents are then downloaded
+	// serially, and decoded using the Decompressor registered for their encoding. Empty means the header is not set.
+	AcceptEncoding string
+
+	// Decide whether or not to write the contents of serial downloads as-is, rather than transparently decompress
+	// them should they be encoded using an encoding that a Decompressor is registered for.
+	DisableDecompression bool
+
+	// Max number of bytes a response body may decompress into before the download fails with
+	// ErrDecompressedTooLarge. A tiny compressed body may decompress into gigabytes, so it should be set when
+	// downloading from untrusted servers. Zero means unlimited.
+	MaxDecompressedSize int64
+
+	// Size of the buffer that the body of a serial download is copied through into its writer, such that a slow
+	// writer is written to in pieces of at most this many bytes. Larger buffers make for fewer writes, while smaller
+	// buffers make for less memory held per download. Buffers are pooled across downloads. Defaults to 32 KiB.
+	CopyBufferSize int
+
+	// Decide whether or not downloads fail with a *HTTPError should a response have a status code of 400 or above.
+	CheckStatus bool
+
+	// Decide whether or not requests for file:// URLs, or for plain local paths such as "/tmp/file.bin", are served
+	// from the local filesystem instead of being sent through the Transport. Files served have their size reported as
+	// their content length, and accept requests for byte ranges, such that they may be downloaded in chunks. Redirects
+	// to local files are never followed. It must only be set should the URLs downloaded be trusted, as it otherwise
+	// allows for arbitrary local files to be read.
+	AllowFileURLs bool
+
+	// Cache that the contents downloaded by DownloadBytes are stored into and served from, keyed by URL. Fresh
+	// contents, as decided by the Cache-Control header they were served with, are served without making any request.
+	// Stale contents that were served with an ETag are revalidated using If-None-Match. Downloads made with
+	// conditional options, or that request the headers of their URL, bypass the cache. Nil means nothing is cached.
+	Cache Cache
+
+	// Headers set on every request sent, unless a request already has the header set (i.e. Range on requests for
+	// byte ranges).
+	Header map[string]string
+
+	// Hook called before a redirect is followed with the request about to be sent, and the URIs of all requests sent
+	// so far, oldest first. Returning an error stops redirects from being followed, and has the error be returned.
+	// Returning ErrUseLastResponse instead has the redirect response be returned as-is with no error.
+	CheckRedirect func(req *fasthttp.Request, via []*fasthttp.URI) error
+
+	// Max duration of the request made to query the headers of a URL before downloading it, independent of the
+	// deadline of the download itself. Downloads fail with ErrHeadTimeout should it be exceeded. Zero means that the
+	// request is only bounded by the deadline of the download.
+	HeadTimeout time.Duration
+
+	// Method of the request made to query the headers of a URL before downloading it, for servers that reject HEAD
+	// requests but serve the headers needed in response to another method, such as OPTIONS. GET requests are sent
+	// for the first byte of the contents only, such that the length of the contents is learned from the Content-Range
+	// header of the response without the contents being downloaded. Queries made using any method other than HEAD
+	// fail with ErrProbeIncomplete should the response yield neither the length of the contents nor whether they may
+	// be requested in byte ranges. Defaults to HEAD should it be empty.
+	ProbeMethod string
+
+	// Decide whether or not the Authorization, Proxy-Authorization, and Cookie headers of a request are kept when
+	// following a redirect to a different host. They are stripped away by default so that credentials are not leaked.
+	KeepSensitiveHeadersOnRedirect bool
+
+	// Cookie jar that cookies set by responses are stored into, and that cookies are attached to requests and
+	// redirects from, scoped by domain and path. A jar from net/http/cookiejar may be used. Cookies are neither
+	// stored nor sent should it be nil.
+	Jar http.CookieJar
+
+	// Decide whether or not redirects to a host other than the host of the original request are refused, in which
+	// case the request fails. Redirects that only change the scheme or path of a request are still followed.
+	SameHostRedirectsOnly bool
+
+	// Max number of redirects to follow before a request is marked to have failed. Zero means that redirects are not
+	// followed, and that redirect responses are returned as-is. A negative value means that an unlimited number of
+	// redirects are followed.
 	MaxRedirectCount int
+
+	// Max number of times a request is retried should the underlying transport have no free connections available to
+	// send it with. Retries are spaced out by Backoff.
+	MaxNoFreeConnsRetries int
+
+	// Decide whether or not requests that are responded to with a status code of 429 or 503 are retried, so long as
+	// the deadline of the request permits. Retries are made after the duration indicated by the Retry-After header of
+	// the response, or after a duration decided by Backoff should the response not have one. Requests whose body is
+	// a stream, such as those sent by UploadFile, are never retried, as their body may not be sent again.
+	RespectRetryAfter bool
+
+	// Max number of times a request is retried as a result of it being responded to with a status code of 429 or 503.
+	MaxRetries int
+
+	// Backoff decides how long to wait before retrying a request. It is consulted by every request made by the
+	// client, including requests for headers and requests for chunks, such that each chunk of a download is retried
+	// independently. Should it be nil, retries are spaced out by an exponentially-increasing backoff with full jitter.
+	Backoff Backoff
+
+	// CircuitBreaker decides whether or not requests may be sent to a host, i.e. to have requests to a host that has
+	// gone down fail fast with ErrCircuitOpen rather than time out. It is consulted before every request made by the
+	// client, including requests for headers and requests for chunks, as well as requests that follow a redirect. It
+	// is shared by clones of the client. Nil means requests are always sent.
+	CircuitBreaker CircuitBreaker
+
+	// Max number of bytes that may be transferred over the lifetime of the client before all further requests fail
+	// with ErrEgressBudgetExceeded. Zero means unlimited.
+	MaxEgressBytes int64
+
+	// Max number of response body bytes that may be written per second, shared across all workers and downloads of
+	// the client. Zero means unlimited.
+	MaxBytesPerSecond int64
+
+	// Hook called before every request is sent, including requests for chunks, requests sent after following a
+	// redirect, and retried requests. Nothing is called should it be nil.
+	OnRequest func(req *fasthttp.Request)
+
+	// Hook called right before every request is sent, which may modify the request, i.e. to sign it or to set headers
+	// that depend on its URL or on the time it is sent at. It is called for requests for chunks once their Range header
+	// has been set, for requests sent after following a redirect, and for every retried request, before OnRequest is
+	// called. Should it return an error, the request is not sent, and fails with said error. Nothing is called should
+	// it be nil.
+	BeforeRequest func(req *fasthttp.Request) error
+
+	// Hook called after a response has been received for every request sent, with the error the transport returned
+	// sending it, if any. Nothing is called should it be nil.
+	OnResponse func(req *fasthttp.Request, res *fasthttp.Response, err error)
+
+	// Hook called every time a redirect is followed, with the URL redirected from and to, and the status code of the
+	// redirect response. Nothing is called should it be nil.
+	OnRedirect func(from, to string, statusCode int)
+
+	// Hook called every time a request is about to be retried, with the attempt that failed and the reason it is
+	// being retried. Nothing is called should it be nil.
+	OnRetry func(req *fasthttp.Request, attempt int, err error)
+
+	// Hook called once a download has either completed or failed, with statistics of the download. Nothing is
+	// called should it be nil.
+	OnComplete func(stats DownloadStats)
+
+	// Hook called by DownloadFiles every time any of its jobs makes progress, with the number of bytes downloaded and
+	// the number of bytes expected across all jobs so far. Jobs whose content length is not yet known are excluded
+	// from both done and total until it is. It may be called from multiple goroutines at once. Nothing is called
+	// should it be nil.
+	OnBatchProgress func(done, total int64)
+
+	// State shared across all copies of the client.
+	state *clientState
+
+	// Hook invoked with events emitted throughout the course of a download.
+	emit func(DownloadEvent)
+
+	// Number of bytes of the contents of the download in progress that have been written, if they are being counted.
+	written *int64
+
+	// Statistics of the download in progress, reported to OnComplete once the download is done.
+	stats *downloadStats
+
+	// Validator of the contents of the download in progress, which requests for chunks carry as an If-Range header.
+	ifRange string
+
+	// Host of the URL the download in progress was started from. Requests sent to any other host, i.e. to the URL it
+	// redirected to, have their sensitive headers stripped away.
+	origin string
+
+	// Channel closed once the download in progress is canceled using DownloadHandle.Cancel.
+	canceled <-chan struct{}
+
+	// Clock which the current time is read from, and which backoff and throttling delays are waited out against, such
+	// that timeouts, deadlines, retries and rate limits may be tested against a fake clock. Set using WithClock.
+	// Defaults to the system clock should it be nil.
+	clk Clock
 }
 
 // NewClient instantiates a new nicehttp.Client with sane configuration defaults.
@@ -45,6 +335,34 @@ func NewClient() Client {
 	return WrapClient(new(fasthttp.Client))
 }
 
+// Clone returns a copy of c which may be configured independently of c, i.e. to tweak NumWorkers or to set an extra
+// header for a single download without affecting other goroutines using c.
+//
+// All configuration fields are copied, with Header being deep-copied. The Transport, and thus its connection pool,
+// is shared with c, as are hooks, the cookie jar, the number of bytes transferred counted against MaxEgressBytes,
+// and the rate limit imposed by MaxBytesPerSecond.
+func (c *Client) Clone() Client {
+	cc := *c
+
+	if c.Header != nil {
+		cc.Header = make(map[string]string, len(c.Header))
+		for key, value := range c.Header {
+			cc.Header[key] = value
+		}
+	}
+
+	return cc
+}
+
+// NewClientWith instantiates a new nicehttp.Client with sane defaults, and applies opts over them in order.
+func NewClientWith(opts ...Option) Client {
+	c := NewClient()
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
 // WrapClient wraps an existing fasthttp.Client or Transport into a nicehttp.Client.
 func WrapClient(instance Transport) Client {
 	return Client{
@@ -60,8 +378,29 @@ func WrapClient(instance Transport) Client {
 		// 10 MiB chunks.
 		ChunkSize: 10 * 1024 * 1024,
 
+		// Default to the number of available CPUs.
+		MaxConcurrentDownloads: runtime.NumCPU(),
+
 		// Redirect 16 times at most.
 		MaxRedirectCount: 16,
+
+		// 32 KiB copy buffers.
+		CopyBufferSize: defaultCopyBufferSize,
+
+		// Only accept contents that are not encoded, such that they may be downloaded in parallel chunks.
+		AcceptEncoding: "identity",
+
+		// Retry 8 times at most should there be no free connections available.
+		MaxNoFreeConnsRetries: 8,
+
+		// Retry 3 times at most should a server ask to retry after some duration.
+		MaxRetries: 3,
+
+		// Space retries out by an exponentially-increasing backoff with full jitter, starting at 10ms up to 1s.
+		Backoff: defaultBackoff,
+
+		// Track the number of bytes transferred, and the rate at which they are written.
+		state: new(clientState),
 	}
 }
 
@@ -74,72 +413,664 @@ func (c *Client) Do(req *fasthttp.Request, res *fasthttp.Response) error {
 // DoTimeout sends a HTTP request prescribed in req and populates its results into res. It additionally handles
 // redirects unlike the de-facto Do(req, res) method in fasthttp. It overrides the default timeout set.
 func (c *Client) DoTimeout(req *fasthttp.Request, res *fasthttp.Response, timeout time.Duration) error {
-	return c.DoDeadline(req, res, time.Now().Add(timeout))
+	return c.DoDeadline(req, res, c.clock().Add(timeout))
 }
 
 // DoDeadline sends a HTTP request prescribed in req and populates its results into res. It additionally handles
 // redirects unlike the de-facto Do(req, res) method in fasthttp. It overrides the default timeout set with a deadline.
 func (c *Client) DoDeadline(req *fasthttp.Request, res *fasthttp.Response, deadline time.Time) error {
-	for i := 0; i <= c.MaxRedirectCount; i++ {
-		var err error
+	if c.deadlinePassed(deadline) {
+		return fasthttp.ErrTimeout
+	}
 
-		if deadline.IsZero() {
-			err = c.Instance.Do(req, res)
-		} else {
-			err = c.Instance.DoDeadline(req, res, deadline)
+	c.applyHeader(req)
+
+	host := append([]byte(nil), req.URI().Host()...)
+
+	// Requests sent directly to the URL that the URL a download was started from redirected to must not carry
+	// credentials meant for the latter.
+
+	if c.origin != "" && !c.KeepSensitiveHeadersOnRedirect && !bytes.EqualFold(host, []byte(c.origin)) {
+		stripSensitiveHeaders(req)
+	}
+
+	var (
+		via        []*fasthttp.URI
+		chain      []string
+		jarCookies []string
+	)
+
+	if c.CheckRedirect != nil {
+		defer func() {
+			for _, uri := range via {
+				fasthttp.ReleaseURI(uri)
+			}
+		}()
+	}
+
+	for redirects := 0; ; redirects++ {
+		if c.wasCanceled() {
+			return ErrCanceled
+		}
+
+		if c.MaxEgressBytes > 0 && c.TransferredBytes() > c.MaxEgressBytes {
+			return ErrEgressBudgetExceeded
 		}
 
-		if err != nil {
+		jarCookies = c.setCookies(req, jarCookies)
+
+		if err := c.sendThroughBreaker(req, res, deadline); err != nil {
 			return err
 		}
 
-		if !fasthttp.StatusCodeIsRedirect(res.StatusCode()) {
+		c.saveCookies(req, res)
+
+		if !fasthttp.StatusCodeIsRedirect(res.StatusCode()) || c.MaxRedirectCount == 0 {
 			return nil
 		}
 
+		chain = append(chain, req.URI().String())
+
+		if c.MaxRedirectCount > 0 && redirects >= c.MaxRedirectCount {
+			return &TooManyRedirectsError{Chain: chain}
+		}
+
 		location := res.Header.Peek("Location")
 		if len(location) == 0 {
-			return errors.New("missing 'Location' header after redirect")
+			return &MissingLocationError{URL: chain[len(chain)-1], StatusCode: res.StatusCode()}
+		}
+
+		if c.CheckRedirect != nil {
+			uri := fasthttp.AcquireURI()
+			req.URI().CopyTo(uri)
+			via = append(via, uri)
+		}
+
+		var from string
+		if c.OnRedirect != nil {
+			from = req.URI().String()
+		}
+
+		if err := rewriteForRedirect(req, res.StatusCode()); err != nil {
+			return err
 		}
 
 		req.URI().UpdateBytes(location)
 
+		if c.AllowFileURLs && isFileRequest(req) {
+			return fmt.Errorf("refusing to follow redirect to a local file %q", req.URI().Path())
+		}
+
+		if c.SameHostRedirectsOnly && !bytes.EqualFold(req.URI().Host(), host) {
+			return fmt.Errorf("refusing to follow redirect from host %q to a different host %q", host, req.URI().Host())
+		}
+
+		if !c.KeepSensitiveHeadersOnRedirect && !bytes.EqualFold(req.URI().Host(), host) {
+			stripSensitiveHeaders(req)
+		}
+
+		if c.CheckRedirect != nil {
+			if err := c.CheckRedirect(req, via); err != nil {
+				if errors.Is(err, ErrUseLastResponse) {
+					via[len(via)-1].CopyTo(req.URI())
+					return nil
+				}
+				return err
+			}
+		}
+
+		if c.OnRedirect != nil {
+			c.OnRedirect(from, req.URI().String(), res.StatusCode())
+		}
+
 		res.Reset()
 	}
+}
+
+// rewriteForRedirect rewrites the method and body of req such that it may be sent to follow a redirect response with
+// the given status code. A 303 turns req into a GET request with no body. A 301 or 302 turns a POST request into a GET
+// request with no body, as all browsers do. A 307 or 308 preserves both the method and body of req, which may not be
+// done should the body of req be a stream that has already been read.
+func rewriteForRedirect(req *fasthttp.Request, statusCode int) error {
+	method := string(req.Header.Method())
+
+	switch statusCode {
+	case fasthttp.StatusSeeOther:
+		if method != fasthttp.MethodGet && method != fasthttp.MethodHead {
+			req.Header.SetMethod(fasthttp.MethodGet)
+		}
+	case fasthttp.StatusMovedPermanently, fasthttp.StatusFound:
+		if method != fasthttp.MethodPost {
+			return nil
+		}
+		req.Header.SetMethod(fasthttp.MethodGet)
+	default:
+		if req.IsBodyStream() {
+			return fmt.Errorf("cannot follow redirect with status code %d with a request body stream", statusCode)
+		}
+		return nil
+	}
+
+	req.ResetBody()
+	req.Header.Del("Content-Type")
+
+	return nil
+}
+
+// stripSensitiveHeaders removes from req all headers that may carry credentials.
+func stripSensitiveHeaders(req *fasthttp.Request) {
+	req.Header.Del("Authorization")
+	req.Header.Del("Proxy-Authorization")
+	req.Header.Del("Cookie")
+	req.Header.DelAllCookies()
+}
+
+// SetHeader sets a header that is to be set on every request sent by c.
+func (c *Client) SetHeader(key, value string) {
+	if c.Header == nil {
+		c.Header = make(map[string]string)
+	}
+	c.Header[key] = value
+}
+
+// applyHeader sets c.Header on req, skipping over headers that are already set on req.
+func (c *Client) applyHeader(req *fasthttp.Request) {
+	for key, value := range c.Header {
+		if len(req.Header.Peek(key)) == 0 {
+			req.Header.Set(key, value)
+		}
+	}
+
+	if c.AcceptEncoding != "" && len(req.Header.Peek("Accept-Encoding")) == 0 {
+		req.Header.Set("Accept-Encoding", c.AcceptEncoding)
+	}
+}
+
+// send sends a HTTP request prescribed in req using the underlying transport and populates its results into res.
+// Should the underlying transport have no free connections available, the request is retried after a delay decided by
+// c.Backoff up to c.MaxNoFreeConnsRetries times. Should c.RespectRetryAfter be set, and the response have a status
+// code of 429 or 503, the request is retried after the duration indicated by its Retry-After header, or after a delay
+// decided by c.Backoff should it not have one, up to c.MaxRetries times. Requests whose body is a stream are not
+// retried as such, as their body has already been read by the time the response arrives, and the response is
+// returned as-is instead. ErrCanceled is returned should the download in progress be canceled while waiting to retry.
+func (c *Client) send(req *fasthttp.Request, res *fasthttp.Response, deadline time.Time) error {
+	var (
+		noFreeConnsRetries int
+		retries            int
+	)
+
+	backoff := c.backoff()
+
+	for attempt := 1; ; attempt++ {
+		var err error
+
+		if c.BeforeRequest != nil {
+			if err := c.BeforeRequest(req); err != nil {
+				return fmt.Errorf("before request hook failed: %w", err)
+			}
+		}
+
+		if c.OnRequest != nil {
+			c.OnRequest(req)
+		}
+
+		if c.AllowFileURLs && isFileRequest(req) {
+			err = serveFile(req, res)
+		} else {
+			var release func()
+
+			if release, err = c.acquire(deadline); err != nil {
+				return err
+			}
+
+			if deadline.IsZero() {
+				err = c.Instance.Do(req, res)
+			} else {
+				err = c.Instance.DoDeadline(req, res, deadline)
+			}
+
+			release()
+
+			if err != nil {
+				err = &NetworkError{URL: req.URI().String(), Err: err}
+			}
+		}
+
+		if c.OnResponse != nil {
+			c.OnResponse(req, res, err)
+		}
+
+		if c.state != nil {
+			atomic.AddInt64(&c.state.transferred, int64(len(res.Body())))
+		}
+
+		var delay time.Duration
+
+		switch {
+		case errors.Is(err, fasthttp.ErrNoFreeConns) && noFreeConnsRetries < c.MaxNoFreeConnsRetries:
+			noFreeConnsRetries++
+
+			delay = backoff.NextDelay(noFreeConnsRetries)
+		case err == nil && c.RespectRetryAfter && retries < c.MaxRetries && !req.IsBodyStream():
+			status := res.StatusCode()
+			if status != fasthttp.StatusTooManyRequests && status != fasthttp.StatusServiceUnavailable {
+				return nil
+			}
+
+			retries++
+
+			var ok bool
+
+			if delay, ok = parseRetryAfter(res.Header.Peek("Retry-After"), c.clock()); !ok {
+				delay = backoff.NextDelay(retries)
+			}
+
+			err = fmt.Errorf("server responded with status code %d, and asked to retry after %s", status, delay)
+		default:
+			return err
+		}
+
+		if !deadline.IsZero() && c.clock().Add(delay).After(deadline) {
+			if errors.Is(err, fasthttp.ErrNoFreeConns) {
+				return err
+			}
+			return nil
+		}
+
+		if c.OnRetry != nil {
+			c.OnRetry(req, attempt, err)
+		}
+
+		if c.stats != nil {
+			atomic.AddInt64(&c.stats.retries, 1)
+		}
+
+		c.event(Retry{Attempt: attempt, Err: err})
+
+		if err := c.sleep(delay); err != nil {
+			return err
+		}
+	}
+}
+
+// acquire acquires a unit of c.Semaphore, blocking until either one is available, deadline passes, or the download in
+// progress is canceled. It returns a func which releases the unit acquired. It is a no-op should c.Semaphore be nil.
+func (c *Client) acquire(deadline time.Time) (func(), error) {
+	if c.Semaphore == nil {
+		return func() {}, nil
+	}
+
+	ctx, cancel := c.cancelable(context.Background())
+	defer cancel()
+
+	if !deadline.IsZero() {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	if err := c.Semaphore.Acquire(ctx, 1); err != nil {
+		if c.wasCanceled() {
+			return nil, ErrCanceled
+		}
+		return nil, contextError(ctx)
+	}
 
-	return errors.New("redirected too many times")
+	return func() { c.Semaphore.Release(1) }, nil
 }
 
-// QueryHeaders learns from url its content length, and if it accepts parallel chunk fetching.
+// parseRetryAfter parses the value of a Retry-After header, which is either a number of seconds or a HTTP date, into
+// a duration relative to now.
+func parseRetryAfter(value []byte, now time.Time) (time.Duration, bool) {
+	value = bytes.TrimSpace(value)
+	if len(value) == 0 {
+		return 0, false
+	}
+
+	if seconds, err := strconv.ParseUint(string(value), 10, 32); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := fasthttp.ParseHTTPDate(value)
+	if err != nil {
+		return 0, false
+	}
+
+	if delay := date.Sub(now); delay > 0 {
+		return delay, true
+	}
+
+	return 0, true
+}
+
+// TransferredBytes returns the total number of response body bytes transferred by c over its lifetime. Bytes are only
+// tracked for clients instantiated using NewClient or WrapClient.
+func (c *Client) TransferredBytes() int64 {
+	if c.state == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&c.state.transferred)
+}
+
+// QueryHeaders learns from url its content length, and if it accepts parallel chunk fetching. URLs whose contents are
+// encoded (i.e. compressed using gzip) are reported to not accept parallel chunk fetching. Should the headers of url
+// fail to be queried, zero values are returned. Use QueryHeadersErr to tell a failed query apart from them.
 func (c *Client) QueryHeaders(url string) (contentLength int, acceptsRanges bool) {
 	return c.QueryHeadersDeadline(url, zeroTime)
 }
 
 // QueryHeadersTimeout learns from url its content length, and if it accepts parallel chunk fetching.
 func (c *Client) QueryHeadersTimeout(url string, timeout time.Duration) (contentLength int, acceptsRanges bool) {
-	return c.QueryHeadersDeadline(url, time.Now().Add(timeout))
+	return c.QueryHeadersDeadline(url, c.clock().Add(timeout))
 }
 
 // QueryHeadersDeadline learns from url its content length, and if it accepts parallel chunk fetching.
 func (c *Client) QueryHeadersDeadline(url string, deadline time.Time) (contentLength int, acceptsRanges bool) {
+	info, _ := c.QueryInfoDeadline(url, deadline)
+	return info.ContentLength, info.AcceptsRanges
+}
+
+// QueryHeadersErr learns from url its content length, and if it accepts parallel chunk fetching. Unlike QueryHeaders,
+// it returns an error should the headers of url fail to be queried, i.e. should url fail to be resolved or connected
+// to, such that a failed query may be told apart from a URL whose contents are empty.
+func (c *Client) QueryHeadersErr(url string) (contentLength int, acceptsRanges bool, err error) {
+	return c.QueryHeadersErrDeadline(url, zeroTime)
+}
+
+// QueryHeadersErrTimeout learns from url its content length, and if it accepts parallel chunk fetching. It returns
+// an error should the headers of url fail to be queried.
+func (c *Client) QueryHeadersErrTimeout(url string, timeout time.Duration) (contentLength int, acceptsRanges bool, err error) {
+	return c.QueryHeadersErrDeadline(url, c.clock().Add(timeout))
+}
+
+// QueryHeadersErrDeadline learns from url its content length, and if it accepts parallel chunk fetching. It returns
+// an error should the headers of url fail to be queried.
+func (c *Client) QueryHeadersErrDeadline(url string, deadline time.Time) (contentLength int, acceptsRanges bool, err error) {
+	return c.queryHeadersContext(context.Background(), url, deadline)
+}
+
+// QueryHeadersContext learns from url its content length, and if it accepts parallel chunk fetching. It returns an
+// error should the headers of url fail to be queried, or should ctx be canceled or pass its deadline beforehand, in
+// which case the error wraps the error of ctx.
+//
+// Redirects and retries stop being followed once ctx is canceled. The request in flight at the time ctx is canceled
+// may not be interrupted, and is left to complete in the background; set a deadline on ctx to bound it.
+func (c *Client) QueryHeadersContext(ctx context.Context, url string) (contentLength int, acceptsRanges bool, err error) {
+	deadline, _ := ctx.Deadline()
+	return c.queryHeadersContext(ctx, url, deadline)
+}
+
+// queryHeadersContext learns from url its content length, and if it accepts parallel chunk fetching, returning early
+// should ctx be canceled. The headers of url are queried in the calling goroutine should ctx never be canceled.
+func (c *Client) queryHeadersContext(ctx context.Context, url string, deadline time.Time) (int, bool, error) {
+	if ctx.Done() == nil {
+		info, err := c.QueryInfoDeadline(url, deadline)
+		return info.ContentLength, info.AcceptsRanges, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return 0, false, fmt.Errorf("failed to query headers of %q: %w", url, err)
+	}
+
+	cc := *c
+	cc.canceled = ctx.Done()
+
+	type result struct {
+		info Info
+		err  error
+	}
+
+	ch := make(chan result, 1)
+
+	go func() {
+		info, err := cc.QueryInfoDeadline(url, deadline)
+		ch <- result{info: info, err: err}
+	}()
+
+	select {
+	case r := <-ch:
+		return r.info.ContentLength, r.info.AcceptsRanges, r.err
+	case <-ctx.Done():
+		return 0, false, fmt.Errorf("failed to query headers of %q: %w", url, ctx.Err())
+	}
+}
+
+// Info describes a URL as learned from its headers by QueryInfo.
+type Info struct {
+	// Content length of the URL. Zero should it not be known.
+	ContentLength int
+
+	// Whether or not the URL accepts being downloaded in parallel chunks. URLs whose contents are encoded (i.e.
+	// compressed using gzip) are reported to not accept being downloaded in parallel chunks.
+	AcceptsRanges bool
+
+	// Values of the Content-Type, ETag and Last-Modified headers of the URL, if any.
+	ContentType  string
+	ETag         string
+	LastModified string
+
+	// The URL that the URL redirected to, or the URL itself should it not have redirected.
+	ResolvedURL string
+}
+
+// QueryInfo learns from url its content length, if it accepts parallel chunk fetching, its content type, its
+// validators, and the URL it redirects to. Unlike QueryHeaders, it returns an error should the headers of url fail to
+// be queried, such that a failed query may be told apart from a URL whose contents are empty.
+func (c *Client) QueryInfo(url string) (Info, error) {
+	return c.QueryInfoDeadline(url, zeroTime)
+}
+
+// QueryInfoTimeout learns from url its content length, if it accepts parallel chunk fetching, its content type, its
+// validators, and the URL it redirects to.
+func (c *Client) QueryInfoTimeout(url string, timeout time.Duration) (Info, error) {
+	return c.QueryInfoDeadline(url, c.clock().Add(timeout))
+}
+
+// QueryInfoDeadline learns from url its content length, if it accepts parallel chunk fetching, its content type, its
+// validators, and the URL it redirects to.
+func (c *Client) QueryInfoDeadline(url string, deadline time.Time) (Info, error) {
+	info, err := c.queryInfo(url, deadline, nil)
+	if err != nil {
+		return Info{}, fmt.Errorf("failed to query headers of %q: %w", url, err)
+	}
+
+	return Info{
+		ContentLength: info.contentLength,
+		AcceptsRanges: info.acceptsRanges,
+		ContentType:   info.contentType,
+		ETag:          info.etag,
+		LastModified:  info.lastModified,
+		ResolvedURL:   info.target(url),
+	}, nil
+}
+
+// resourceInfo describes a URL as learned from the headers of a HEAD request made to it.
+type resourceInfo struct {
+	url                string
+	contentLength      int
+	acceptsRanges      bool
+	etag               string
+	lastModified       string
+	contentType        string
+	contentDisposition string
+}
+
+// validator returns the value of the If-Range header that requests for byte ranges of the contents described by i
+// carry. It is the ETag of the contents should it be strong, as If-Range does not permit weak ETags, and otherwise
+// the Last-Modified date of the contents. It is empty should the contents report neither.
+func (i resourceInfo) validator() string {
+	if i.etag != "" && !strings.HasPrefix(i.etag, "W/") {
+		return i.etag
+	}
+	return i.lastModified
+}
+
+// forTarget returns a copy of c which downloads the contents of url described by info from the URL url redirected
+// to. Requests for chunks carry an If-Range header with the validator of the contents, such that a change to the
+// contents mid-download fails the download with ErrContentChanged. Requests sent to a host other than the host of
+// url have their sensitive headers stripped away the same way they would be had they followed the redirect to it.
+func (c *Client) forTarget(url string, info resourceInfo) *Client {
+	cc := *c.withOrigin(url)
+	cc.ifRange = info.validator()
+	return &cc
+}
+
+// withOrigin returns a copy of c whose requests sent to a host other than the host of url have their sensitive
+// headers stripped away. It returns c as-is should c already have an origin, which is then the host the download in
+// progress was started from.
+func (c *Client) withOrigin(url string) *Client {
+	if c.origin != "" {
+		return c
+	}
+
+	uri := fasthttp.AcquireURI()
+	defer fasthttp.ReleaseURI(uri)
+
+	uri.Update(url)
+
+	cc := *c
+	cc.origin = string(uri.Host())
+
+	return &cc
+}
+
+// queryFailed reports whether or not err, as returned by queryInfo, fails the download of the URL whose headers were
+// being queried. Servers that respond to a HEAD request with an error status code may still serve a GET request, so
+// downloads only carry on serially should the query have failed with a *HTTPError.
+func queryFailed(err error) bool {
+	var httpErr *HTTPError
+	return err != nil && !errors.As(err, &httpErr)
+}
+
+// target returns the URL that url was redirected to as reported by i, such that the contents of url may be downloaded
+// without having to follow its redirects again. It returns url as-is should i not report the URL it redirected to.
+func (i resourceInfo) target(url string) string {
+	if i.url == "" {
+		return url
+	}
+	return i.url
+}
+
+// queryInfo learns from url its content length, if it accepts parallel chunk fetching, and its validators. The
+// headers of url are copied into header should header not be nil.
+func (c *Client) queryInfo(url string, deadline time.Time, header *fasthttp.ResponseHeader) (info resourceInfo, err error) {
 	req := fasthttp.AcquireRequest()
 	defer fasthttp.ReleaseRequest(req)
 
 	res := fasthttp.AcquireResponse()
 	defer fasthttp.ReleaseResponse(res)
 
-	req.Header.SetMethod(fasthttp.MethodHead)
+	method := strings.ToUpper(c.ProbeMethod)
+	if method == "" {
+		method = fasthttp.MethodHead
+	}
+
+	req.Header.SetMethod(method)
 	req.SetRequestURI(url)
 
-	if err := c.DoDeadline(req, res, deadline); err == nil {
-		if contentLength = res.Header.ContentLength(); contentLength <= 0 {
-			contentLength = 0
+	if method == fasthttp.MethodGet {
+		req.Header.SetByteRange(0, 0)
+	}
+
+	if c.HeadTimeout > 0 {
+		if d := c.clock().Add(c.HeadTimeout); deadline.IsZero() || d.Before(deadline) {
+			deadline = d
+		}
+	}
+
+	send := func() error {
+		err := c.DoDeadline(req, res, deadline)
+		if err != nil && c.HeadTimeout > 0 && errors.Is(err, fasthttp.ErrTimeout) {
+			return fmt.Errorf("%w after %s: %v", ErrHeadTimeout, c.HeadTimeout, err)
+		}
+		return err
+	}
+
+	if err := send(); err != nil {
+		return info, err
+	}
+
+	// Some servers do not allow for HEAD requests. Request for the first byte of the contents instead, and learn the
+	// content length from the Content-Range header of the response.
+
+	ranged := method == fasthttp.MethodGet && res.StatusCode() == fasthttp.StatusPartialContent
+
+	if status := res.StatusCode(); method != fasthttp.MethodGet && (status == fasthttp.StatusMethodNotAllowed || status == fasthttp.StatusNotImplemented) {
+		req.Header.SetMethod(fasthttp.MethodGet)
+		req.Header.SetByteRange(0, 0)
+		res.Reset()
+
+		if err := send(); err != nil {
+			return info, err
+		}
+
+		ranged = res.StatusCode() == fasthttp.StatusPartialContent
+	}
+
+	if res.StatusCode() == fasthttp.StatusNotModified {
+		if header != nil {
+			res.Header.CopyTo(header)
+		}
+		return info, ErrNotModified
+	}
+
+	if err := c.checkStatus(res); err != nil {
+		return info, err
+	}
+
+	if header != nil {
+		res.Header.CopyTo(header)
+	}
+
+	info.url = req.URI().String()
+
+	if info.contentLength = res.Header.ContentLength(); info.contentLength <= 0 {
+		info.contentLength = 0
+	}
+
+	// Byte ranges of encoded contents do not map onto the decoded contents, so contents that are encoded may not be
+	// downloaded in parallel chunks.
+
+	info.acceptsRanges = bytesutil.String(res.Header.Peek("Accept-Ranges")) == "bytes" && contentEncoding(&res.Header) == ""
+
+	if ranged {
+		info.contentLength = 0
+		if _, _, total, ok := parseContentRange(res.Header.Peek("Content-Range")); ok && total > 0 {
+			info.contentLength = total
+		}
+		info.acceptsRanges = contentEncoding(&res.Header) == ""
+	}
+
+	// Some servers that serve byte ranges do not report a content length in response to a HEAD request. Learn the
+	// content length from the Content-Range header of a request for the first byte of the contents instead. Only
+	// servers that advertise serving byte ranges are asked, as servers that ignore the Range header would otherwise
+	// respond with the entirety of their contents. The request is sent straight to the URL url redirected to.
+
+	if !ranged && info.contentLength == 0 && c.AcceptsRanges && info.acceptsRanges {
+		if total, err := c.withOrigin(url).ProbeContentLengthDeadline(info.target(url), deadline); err == nil && total > 0 {
+			info.contentLength = total
+			info.acceptsRanges = true
 		}
+	}
+
+	if method != fasthttp.MethodHead && info.contentLength == 0 && !info.acceptsRanges {
+		return info, fmt.Errorf("querying the headers of %q using %s: %w", url, method, ErrProbeIncomplete)
+	}
+
+	// Contents requested while advertising an encoding may be served encoded by requests for chunks even if they
+	// were not served encoded in response to the HEAD request.
 
-		acceptsRanges = bytesutil.String(res.Header.Peek("Accept-Ranges")) == "bytes"
+	if c.advertisesEncoding() {
+		info.acceptsRanges = false
 	}
 
-	return contentLength, acceptsRanges
+	info.etag = string(res.Header.Peek("ETag"))
+	info.lastModified = string(res.Header.Peek("Last-Modified"))
+	info.contentType = string(res.Header.ContentType())
+	info.contentDisposition = string(res.Header.Peek("Content-Disposition"))
+
+	return info, nil
 }
 
 // Download downloads the contents of url and writes its contents to w.
@@ -149,24 +1080,43 @@ func (c *Client) Download(w Writer, url string, contentLength int, acceptsRanges
 
 // DownloadTimeout downloads the contents of url and writes its contents to w.
 func (c *Client) DownloadTimeout(w Writer, url string, contentLength int, acceptsRanges bool, timeout time.Duration) error {
-	return c.DownloadDeadline(w, url, contentLength, acceptsRanges, time.Now().Add(timeout))
+	return c.DownloadDeadline(w, url, contentLength, acceptsRanges, c.clock().Add(timeout))
 }
 
 // DownloadDeadline downloads the contents of url and writes its contents to w.
 func (c *Client) DownloadDeadline(w Writer, url string, contentLength int, acceptsRanges bool, deadline time.Time) error {
+	return c.download(w, url, contentLength, acceptsRanges, deadline, nil)
+}
+
+// download downloads the contents of url and writes its contents to w. Should the contents of url be downloaded
+// serially, the headers of the response are copied into header should header not be nil. Should the server ignore
+// requests for byte ranges or encode them, the contents of url are downloaded serially instead.
+func (c *Client) download(w Writer, url string, contentLength int, acceptsRanges bool, deadline time.Time, header *fasthttp.ResponseHeader) (err error) {
+	c, done := c.trackStats(url)
+	defer func() { done(err) }()
+
 	if c.AcceptsRanges && acceptsRanges {
+		c.tookPath(true)
+
 		if contentLength <= 0 {
 			return fmt.Errorf("content length is %d - see doc for (*fasthttp.ResponseHeader).ContentLength()", contentLength)
 		}
 
-		if err := c.DownloadInChunksDeadline(w, url, contentLength, deadline); err != nil {
-			return err
+		c.event(Started{URL: url, ContentLength: contentLength, Chunked: true})
+
+		err := c.downloadInChunks(w, []string{url}, contentLength, deadline, nil)
+		if errors.Is(err, ErrRangeIgnored) || errors.Is(err, ErrRangeEncoded) {
+			c.tookPath(false)
+
+			return c.downloadSerially(NewWriterAtOffset(w, 0), url, contentLength, deadline, header)
 		}
 
-		return nil
+		return err
 	}
 
-	if err := c.DownloadSeriallyDeadline(w, url, deadline); err != nil {
+	c.event(Started{URL: url, ContentLength: contentLength})
+
+	if err := c.downloadSerially(w, url, contentLength, deadline, header); err != nil {
 		return err
 	}
 
@@ -174,52 +1124,293 @@ func (c *Client) DownloadDeadline(w Writer, url string, contentLength int, accep
 }
 
 // DownloadBytes downloads the contents of url, and returns them as a byte slice.
-func (c *Client) DownloadBytes(dst []byte, url string) ([]byte, error) {
-	return c.DownloadBytesDeadline(dst, url, zeroTime)
+func (c *Client) DownloadBytes(dst []byte, url string, opts ...DownloadOption) ([]byte, error) {
+	return c.DownloadBytesDeadline(dst, url, zeroTime, opts...)
 }
 
 // DownloadBytesTimeout downloads the contents of url, and returns them as a byte slice.
-func (c *Client) DownloadBytesTimeout(dst []byte, url string, timeout time.Duration) ([]byte, error) {
-	return c.DownloadBytesDeadline(dst, url, time.Now().Add(timeout))
+func (c *Client) DownloadBytesTimeout(dst []byte, url string, timeout time.Duration, opts ...DownloadOption) ([]byte, error) {
+	return c.DownloadBytesDeadline(dst, url, c.clock().Add(timeout), opts...)
 }
 
 // DownloadBytesDeadline downloads the contents of url, and returns them as a byte slice.
-func (c *Client) DownloadBytesDeadline(dst []byte, url string, deadline time.Time) ([]byte, error) {
-	contentLength, acceptsRanges := c.QueryHeadersDeadline(url, deadline)
+func (c *Client) DownloadBytesDeadline(dst []byte, url string, deadline time.Time, opts ...DownloadOption) ([]byte, error) {
+	return c.downloadBytes(dst, url, deadline, nil, newDownloadOptions(opts))
+}
+
+// DownloadBytesWithHeader downloads the contents of url, and returns them as a byte slice alongside a copy of the
+// headers of url. Should the contents of url be downloaded in parallel chunks, the headers are those of the HEAD
+// request made to url. Otherwise, the headers are those of the response the contents of url were read from.
+func (c *Client) DownloadBytesWithHeader(dst []byte, url string) ([]byte, *fasthttp.ResponseHeader, error) {
+	return c.DownloadBytesWithHeaderDeadline(dst, url, zeroTime)
+}
 
-	w := NewWriteBuffer(bytesutil.ExtendSlice(dst, contentLength))
+// DownloadBytesWithHeaderTimeout downloads the contents of url, and returns them as a byte slice alongside a copy of
+// the headers of url.
+func (c *Client) DownloadBytesWithHeaderTimeout(dst []byte, url string, timeout time.Duration) ([]byte, *fasthttp.ResponseHeader, error) {
+	return c.DownloadBytesWithHeaderDeadline(dst, url, c.clock().Add(timeout))
+}
 
-	if err := c.DownloadDeadline(w, url, contentLength, acceptsRanges, deadline); err != nil {
-		return w.dst, err
+// DownloadBytesWithHeaderDeadline downloads the contents of url, and returns them as a byte slice alongside a copy of
+// the headers of url.
+func (c *Client) DownloadBytesWithHeaderDeadline(dst []byte, url string, deadline time.Time) ([]byte, *fasthttp.ResponseHeader, error) {
+	header := new(fasthttp.ResponseHeader)
+
+	buf, err := c.downloadBytes(dst, url, deadline, header, downloadOptions{})
+	if err != nil {
+		return buf, nil, err
 	}
 
-	return w.dst, nil
+	return buf, header, nil
+}
+
+// downloadBytes downloads the contents of url, and returns them as a byte slice. The headers of url are copied into
+// header should header not be nil.
+func (c *Client) downloadBytes(dst []byte, url string, deadline time.Time, header *fasthttp.ResponseHeader, o downloadOptions) ([]byte, error) {
+	if c.Cache != nil && header == nil && o.ifNoneMatch == "" && o.ifModifiedSince == "" {
+		return c.downloadBytesCached(dst, url, deadline, o)
+	}
+
+	return c.fetchBytes(dst, url, deadline, header, o)
+}
+
+// fetchBytes downloads the contents of url over the network, and returns them as a byte slice. The headers of url are
+// copied into header should header not be nil.
+func (c *Client) fetchBytes(dst []byte, url string, deadline time.Time, header *fasthttp.ResponseHeader, o downloadOptions) ([]byte, error) {
+	c = c.conditional(o)
+
+	info, err := c.queryInfo(url, deadline, header)
+	if queryFailed(err) {
+		return dst, err
+	}
+
+	c = c.forTarget(url, info)
+
+	o.resolve(url, info)
+
+	// Only size the buffer up front should the contents of url be downloaded in parallel chunks. Contents that are
+	// downloaded serially are appended to the buffer instead.
+
+	if o.maxBodySize > 0 && info.contentLength > o.maxBodySize {
+		return dst, fmt.Errorf("contents of %q are %d byte(s), more than the max of %d byte(s): %w", url, info.contentLength, o.maxBodySize, ErrBodyTooLarge)
+	}
+
+	maxSize := o.maxBodySize
+
+	if c.AcceptsRanges && info.acceptsRanges {
+		dst = bytesutil.ExtendSlice(dst, info.contentLength)
+	} else if maxSize > 0 {
+		maxSize += len(dst)
+	}
+
+	w := NewWriteBuffer(dst)
+	w.MaxSize = maxSize
+
+	if err := c.download(w, info.target(url), info.contentLength, info.acceptsRanges, deadline, header); err != nil {
+		return w.Bytes(), err
+	}
+
+	return w.Bytes(), nil
 }
 
 // DownloadFile downloads the contents of url, and writes its contents to a newly-created file titled filename.
-func (c *Client) DownloadFile(filename, url string) error {
-	return c.DownloadFileDeadline(filename, url, zeroTime)
+func (c *Client) DownloadFile(filename, url string, opts ...DownloadOption) error {
+	return c.DownloadFileDeadline(filename, url, zeroTime, opts...)
 }
 
 // DownloadFileTimeout downloads the contents of url, and writes its contents to a newly-created file titled filename.
-func (c *Client) DownloadFileTimeout(filename, url string, timeout time.Duration) error {
-	return c.DownloadFileDeadline(filename, url, time.Now().Add(timeout))
+// It is equivalent to DownloadFileWithin.
+func (c *Client) DownloadFileTimeout(filename, url string, timeout time.Duration, opts ...DownloadOption) error {
+	return c.DownloadFileDeadline(filename, url, c.clock().Add(timeout), opts...)
+}
+
+// DownloadFileWithin downloads the contents of url, and writes its contents to a newly-created file titled filename
+// within a total wall-clock budget. A single deadline is derived from total, which is shared across the request for
+// the headers of url, all requests for chunks, all redirects followed, and all retries. The download either completes
+// or fails with fasthttp.ErrTimeout within total.
+//
+// Note that every Timeout variant of a download derives a single deadline from its timeout the same way. The timeout
+// is never applied to each request individually, unlike the ReadTimeout and WriteTimeout of a *fasthttp.Client.
+func (c *Client) DownloadFileWithin(filename, url string, total time.Duration, opts ...DownloadOption) error {
+	return c.DownloadFileDeadline(filename, url, c.clock().Add(total), opts...)
 }
 
 // DownloadFileDeadline downloads the contents of url, and writes its contents to a newly-created file titled filename.
-func (c *Client) DownloadFileDeadline(filename, url string, deadline time.Time) error {
-	contentLength, acceptsRanges := c.QueryHeadersDeadline(url, deadline)
+//
+// The contents of url are first written to a temporary file in the same directory as filename, which is atomically
+// renamed to filename only once the download has completed. Should the download fail, the temporary file is removed
+// unless c.KeepPartialFiles is set.
+func (c *Client) DownloadFileDeadline(filename, url string, deadline time.Time, opts ...DownloadOption) error {
+	o := newDownloadOptions(opts)
+
+	c = c.conditional(o)
+
+	info, err := c.queryInfo(url, deadline, nil)
+	if queryFailed(err) {
+		return err
+	}
+
+	c = c.forTarget(url, info)
+
+	o.resolve(url, info)
+
+	if err := c.skipIfPresent(filename, info); err != nil {
+		return err
+	}
+
+	if err := c.downloadFile(filename, info.target(url), info.contentLength, info.acceptsRanges, deadline, o); err != nil {
+		return err
+	}
+
+	return c.recordETag(filename, info)
+}
 
-	w, err := os.Create(filename)
+// DownloadFileSized downloads the contents of url comprised of contentLength bytes, and writes its contents to a
+// newly-created file titled filename. Unlike DownloadFile, the headers of url are not queried beforehand, such that
+// contents whose length is already known, i.e. from a manifest, are downloaded without the latency of a HEAD request.
+// The contents are downloaded in parallel chunks should acceptsRanges be set, and serially otherwise.
+func (c *Client) DownloadFileSized(filename, url string, contentLength int, acceptsRanges bool, opts ...DownloadOption) error {
+	return c.DownloadFileSizedDeadline(filename, url, contentLength, acceptsRanges, zeroTime, opts...)
+}
+
+// DownloadFileSizedTimeout downloads the contents of url comprised of contentLength bytes without querying its
+// headers beforehand, and writes its contents to a newly-created file titled filename.
+func (c *Client) DownloadFileSizedTimeout(filename, url string, contentLength int, acceptsRanges bool, timeout time.Duration, opts ...DownloadOption) error {
+	return c.DownloadFileSizedDeadline(filename, url, contentLength, acceptsRanges, c.clock().Add(timeout), opts...)
+}
+
+// DownloadFileSizedDeadline downloads the contents of url comprised of contentLength bytes without querying its
+// headers beforehand, and writes its contents to a newly-created file titled filename.
+func (c *Client) DownloadFileSizedDeadline(filename, url string, contentLength int, acceptsRanges bool, deadline time.Time, opts ...DownloadOption) error {
+	o := newDownloadOptions(opts)
+
+	c = c.conditional(o)
+
+	o.resolve(url, resourceInfo{})
+
+	return c.downloadFile(filename, url, contentLength, acceptsRanges, deadline, o)
+}
+
+// downloadFile downloads the contents of url comprised of contentLength bytes, and writes its contents to a
+// newly-created file titled filename.
+func (c *Client) downloadFile(filename, url string, contentLength int, acceptsRanges bool, deadline time.Time, o downloadOptions) (err error) {
+	if o.maxFileSize > 0 && int64(contentLength) > o.maxFileSize {
+		return fmt.Errorf("contents of %q are %d byte(s), more than the max of %d byte(s): %w", url, contentLength, o.maxFileSize, ErrFileTooLarge)
+	}
+
+	h, err := o.newChecksumHash()
 	if err != nil {
-		return fmt.Errorf("failed to open dest file: %w", err)
+		return err
 	}
 
-	if err := w.Truncate(int64(contentLength)); err != nil {
-		return fmt.Errorf("failed to truncate file to %d byte(s): %w", contentLength, err)
+	f, err := createTempFile(c.TempDir, filename)
+	if err != nil {
+		return fmt.Errorf("failed to open temp file: %w", err)
 	}
 
-	return c.DownloadDeadline(w, url, contentLength, acceptsRanges, deadline)
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		f.Close()
+
+		if !c.KeepPartialFiles {
+			os.Remove(f.Name())
+		}
+	}()
+
+	chunked := c.AcceptsRanges && acceptsRanges
+
+	// Only size the file up front should its contents be written at the offsets of their chunks. The file is newly
+	// created and thus empty, such that contents that are downloaded serially are simply appended to it.
+
+	if chunked && contentLength > 0 {
+		if err := c.allocate(f, int64(contentLength)); err != nil {
+			return fmt.Errorf("failed to allocate %d byte(s) for file: %w", contentLength, err)
+		}
+	}
+
+	var w Writer = f
+	if o.maxFileSize > 0 {
+		w = &fileSizeLimiter{Writer: w, max: o.maxFileSize}
+	}
+	if h != nil && !chunked {
+		w = &hashingWriter{Writer: w, h: h}
+	}
+	if o.hash != nil {
+		w = &hashingWriter{Writer: w, h: o.hash}
+	}
+
+	if err := c.DownloadDeadline(w, url, contentLength, acceptsRanges, deadline); err != nil {
+		return err
+	}
+
+	if h != nil {
+		if chunked {
+			if err := hashFrom(h, f, int64(contentLength)); err != nil {
+				return fmt.Errorf("failed to read back file to verify its checksum: %w", err)
+			}
+		}
+
+		if err := o.verifyChecksum(h); err != nil {
+			return err
+		}
+	}
+
+	if err := c.sync(f); err != nil {
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	if err := c.moveFile(f.Name(), filename); err != nil {
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+
+	return nil
+}
+
+// DownloadFileEvents downloads the contents of url in the background, and writes its contents to a newly-created file
+// titled filename. Events describing the state of the download are sent to the returned channel, which is closed
+// once the download has either completed or failed. Events are never sent in a blocking manner; should the channel
+// not be read from quickly enough, the oldest events are dropped.
+func (c *Client) DownloadFileEvents(filename, url string) (<-chan DownloadEvent, error) {
+	return c.DownloadFileEventsDeadline(filename, url, zeroTime)
+}
+
+// DownloadFileEventsTimeout downloads the contents of url in the background, and writes its contents to a
+// newly-created file titled filename. Events describing the state of the download are sent to the returned channel.
+func (c *Client) DownloadFileEventsTimeout(filename, url string, timeout time.Duration) (<-chan DownloadEvent, error) {
+	return c.DownloadFileEventsDeadline(filename, url, c.clock().Add(timeout))
+}
+
+// DownloadFileEventsDeadline downloads the contents of url in the background, and writes its contents to a
+// newly-created file titled filename. Events describing the state of the download are sent to the returned channel.
+func (c *Client) DownloadFileEventsDeadline(filename, url string, deadline time.Time) (<-chan DownloadEvent, error) {
+	if _, err := os.Stat(filepath.Dir(filename)); err != nil {
+		return nil, fmt.Errorf("failed to stat dest dir: %w", err)
+	}
+
+	sink := newEventSink()
+
+	cc := *c
+	cc.emit = sink.emit
+
+	go func() {
+		defer sink.close()
+
+		if err := cc.DownloadFileDeadline(filename, url, deadline); err != nil {
+			sink.emit(Failed{Err: err})
+			return
+		}
+
+		sink.emit(Completed{})
+	}()
+
+	return sink.ch, nil
 }
 
 // DownloadSerially serially downloads the contents of url and writes it to w.
@@ -229,11 +1420,45 @@ func (c *Client) DownloadSerially(w io.Writer, url string) error {
 
 // DownloadSeriallyTimeout serially downloads the contents of url and writes it to w.
 func (c *Client) DownloadSeriallyTimeout(w io.Writer, url string, timeout time.Duration) error {
-	return c.DownloadSeriallyDeadline(w, url, time.Now().Add(timeout))
+	return c.DownloadSeriallyDeadline(w, url, c.clock().Add(timeout))
 }
 
 // DownloadSeriallyDeadline serially downloads the contents of url and writes it to w.
 func (c *Client) DownloadSeriallyDeadline(w io.Writer, url string, deadline time.Time) error {
+	_, err := c.DownloadSeriallyNDeadline(w, url, deadline)
+	return err
+}
+
+// DownloadSeriallyN serially downloads the contents of url and writes it to w. It returns the number of bytes written
+// to w, which are the decompressed bytes should the contents of url have been decompressed.
+func (c *Client) DownloadSeriallyN(w io.Writer, url string) (int64, error) {
+	return c.DownloadSeriallyNDeadline(w, url, zeroTime)
+}
+
+// DownloadSeriallyNTimeout serially downloads the contents of url and writes it to w. It returns the number of bytes
+// written to w.
+func (c *Client) DownloadSeriallyNTimeout(w io.Writer, url string, timeout time.Duration) (int64, error) {
+	return c.DownloadSeriallyNDeadline(w, url, c.clock().Add(timeout))
+}
+
+// DownloadSeriallyNDeadline serially downloads the contents of url and writes it to w. It returns the number of bytes
+// written to w.
+func (c *Client) DownloadSeriallyNDeadline(w io.Writer, url string, deadline time.Time) (int64, error) {
+	c, written := c.countWritten()
+	err := c.downloadSerially(w, url, 0, deadline, nil)
+	return atomic.LoadInt64(written), err
+}
+
+// downloadSerially serially downloads the contents of url and writes it to w. The headers of the response are copied
+// into header should header not be nil.
+//
+// The number of bytes written to w is checked against length, or against the Content-Length of the response should
+// length not be positive. The check is skipped should the body of the response have been decompressed, or should
+// its length not be known.
+func (c *Client) downloadSerially(w io.Writer, url string, length int, deadline time.Time, header *fasthttp.ResponseHeader) (err error) {
+	c, done := c.trackStats(url)
+	defer func() { done(err) }()
+
 	req := fasthttp.AcquireRequest()
 	defer fasthttp.ReleaseRequest(req)
 
@@ -246,101 +1471,127 @@ func (c *Client) DownloadSeriallyDeadline(w io.Writer, url string, deadline time
 		return fmt.Errorf("failed to download %q: %w", url, err)
 	}
 
-	return res.BodyWriteTo(w)
-}
+	if res.StatusCode() == fasthttp.StatusNotModified {
+		return ErrNotModified
+	}
 
-// DownloadInChunks downloads file at url comprised of length bytes in chunks using multiple workers, and stores it in
-// writer w.
-func (c *Client) DownloadInChunks(f io.WriterAt, url string, length int) error {
-	return c.DownloadInChunksDeadline(f, url, length, zeroTime)
-}
+	if err := c.checkStatus(res); err != nil {
+		return fmt.Errorf("failed to download %q: %w", url, err)
+	}
 
-// DownloadInChunksTimeout downloads file at url comprised of length bytes in chunks using multiple workers, and stores
-// it in writer w.
-func (c *Client) DownloadInChunksTimeout(f io.WriterAt, url string, length int, timeout time.Duration) error {
-	return c.DownloadInChunksDeadline(f, url, length, time.Now().Add(timeout))
-}
+	if header != nil {
+		res.Header.CopyTo(header)
+	}
 
-// DownloadInChunksDeadline downloads file at url comprised of length bytes in chunks using multiple workers, and
-// stores it in writer w.
-func (c *Client) DownloadInChunksDeadline(f io.WriterAt, url string, length int, deadline time.Time) error {
-	timeout := (<-chan time.Time)(nil)
+	// Report progress for every buffer of the body written, the same way downloads in chunks report progress for
+	// every chunk written.
 
-	if t := -time.Since(deadline); t > 0 {
-		timer := fasthttp.AcquireTimer(t)
-		defer fasthttp.ReleaseTimer(timer)
+	total := c.decodedLength(res, length)
 
-		timeout = timer.C
+	n, err := c.writeBody(c.throttleWriter(c.progressWriter(w, total), deadline), res)
+	if err != nil {
+		return err
 	}
 
-	var g errgroup.Group
-
-	// ByteRange represents a byte range.
-	type ByteRange struct{ Start, End int }
+	if c.DisableDecompression || contentEncoding(&res.Header) == "" {
+		if length <= 0 {
+			length = res.Header.ContentLength()
+		}
 
-	ch := make(chan ByteRange, c.NumWorkers)
+		if length >= 0 && n != int64(length) {
+			return fmt.Errorf("failed to download %q: got %d byte(s), expected %d: %w", url, n, length, ErrShortDownload)
+		}
+	}
 
-	// Spawn w workers that will dispatch and execute byte range-inclusive HTTP requests.
+	c.wrote(n)
 
-	for i := 0; i < c.NumWorkers; i++ {
-		i := i
+	// Report the total once the body has been written in its entirety should it not have been known up front, i.e. as
+	// the body was decompressed as it was written.
 
-		g.Go(func() error {
-			req := fasthttp.AcquireRequest()
-			defer fasthttp.ReleaseRequest(req)
+	if total == 0 {
+		c.event(Progress{Done: n, Total: n})
+	}
 
-			res := fasthttp.AcquireResponse()
-			defer fasthttp.ReleaseResponse(res)
+	return nil
+}
 
-			req.SetRequestURI(url)
+// DownloadInChunks downloads file at url comprised of length bytes in chunks using multiple workers, and stores it in
+// writer w.
+func (c *Client) DownloadInChunks(f io.WriterAt, url string, length int) error {
+	return c.DownloadInChunksDeadline(f, url, length, zeroTime)
+}
 
-			for r := range ch {
-				req.Header.SetByteRange(r.Start, r.End)
+// DownloadInChunksTimeout downloads file at url comprised of length bytes in chunks using multiple workers, and stores
+// it in writer w.
+func (c *Client) DownloadInChunksTimeout(f io.WriterAt, url string, length int, timeout time.Duration) error {
+	return c.DownloadInChunksDeadline(f, url, length, c.clock().Add(timeout))
+}
 
-				if err := c.DoDeadline(req, res, deadline); err != nil {
-					return fmt.Errorf("worker %d failed to get bytes range (start: %d, end: %d): %w", i, r.Start, r.End, err)
-				}
+// DownloadInChunksDeadline downloads file at url comprised of length bytes in chunks using multiple workers, and
+// stores it in writer w. Once deadline passes, all workers are canceled and fasthttp.ErrTimeout is returned.
+func (c *Client) DownloadInChunksDeadline(f io.WriterAt, url string, length int, deadline time.Time) error {
+	_, err := c.DownloadInChunksNDeadline(f, url, length, deadline)
+	return err
+}
 
-				if err := res.BodyWriteTo(NewWriterAtOffset(f, int64(r.Start))); err != nil {
-					return fmt.Errorf("worker %d failed to write to file at offset %d: %w", i, r.Start, err)
-				}
-			}
+// DownloadInChunksN downloads file at url comprised of length bytes in chunks using multiple workers, and stores it in
+// writer w. It returns the number of bytes written to w across all workers, including should the download fail.
+func (c *Client) DownloadInChunksN(f io.WriterAt, url string, length int) (int64, error) {
+	return c.DownloadInChunksNDeadline(f, url, length, zeroTime)
+}
 
-			return nil
-		})
-	}
+// DownloadInChunksNTimeout downloads file at url comprised of length bytes in chunks using multiple workers, and
+// stores it in writer w. It returns the number of bytes written to w across all workers.
+func (c *Client) DownloadInChunksNTimeout(f io.WriterAt, url string, length int, timeout time.Duration) (int64, error) {
+	return c.DownloadInChunksNDeadline(f, url, length, c.clock().Add(timeout))
+}
 
-	// Fill up ch with byte ranges to be download from url.
+// DownloadInChunksNDeadline downloads file at url comprised of length bytes in chunks using multiple workers, and
+// stores it in writer w. It returns the number of bytes written to w across all workers.
+func (c *Client) DownloadInChunksNDeadline(f io.WriterAt, url string, length int, deadline time.Time) (int64, error) {
+	c, written := c.countWritten()
+	err := c.downloadInChunks(f, []string{url}, length, deadline, nil)
+	return atomic.LoadInt64(written), err
+}
 
-	var r ByteRange
+// DownloadRanges downloads only the given byte ranges of the contents of url using multiple workers, and stores them
+// in writer f. It is meant to be used to download the byte ranges listed as failed by a *ChunkError once more.
+func (c *Client) DownloadRanges(f io.WriterAt, url string, ranges []ByteRange) error {
+	return c.DownloadRangesDeadline(f, url, ranges, zeroTime)
+}
 
-Feed:
-	for r.End < length {
-		r.End += c.ChunkSize
-		if r.End > length {
-			r.End = length
-		}
+// DownloadRangesTimeout downloads only the given byte ranges of the contents of url using multiple workers, and
+// stores them in writer f.
+func (c *Client) DownloadRangesTimeout(f io.WriterAt, url string, ranges []ByteRange, timeout time.Duration) error {
+	return c.DownloadRangesDeadline(f, url, ranges, c.clock().Add(timeout))
+}
 
-		select {
-		case ch <- r:
-		case <-timeout:
-			break Feed
+// DownloadRangesDeadline downloads only the given byte ranges of the contents of url using multiple workers, and
+// stores them in writer f.
+func (c *Client) DownloadRangesDeadline(f io.WriterAt, url string, ranges []ByteRange, deadline time.Time) error {
+	var length int
+	for _, r := range ranges {
+		if r.Start < 0 || r.End <= r.Start {
+			return fmt.Errorf("invalid byte range (start: %d, end: %d)", r.Start, r.End)
 		}
-
-		r.Start += c.ChunkSize
-		if r.Start > length {
-			r.Start = length
+		if r.End > length {
+			length = r.End
 		}
 	}
 
-	close(ch)
+	return c.downloadRanges(f, []string{url}, ranges, length, deadline, nil)
+}
 
-	// Wait until all byte ranges have been downloaded, or return early if an error was encountered downloading
-	// a chunk.
+// clientState is state that is shared across all copies of a Client.
+type clientState struct {
+	transferred int64
+	bucket      tokenBucket
+	pinned      pinnedHosts
+}
 
-	if err := g.Wait(); err != nil {
-		return fmt.Errorf("failed to download %q in chunks: %w", url, err)
+// event emits e to the hook registered on c for download events, should there be one.
+func (c *Client) event(e DownloadEvent) {
+	if c.emit != nil {
+		c.emit(e)
 	}
-
-	return nil
 }
@@ -0,0 +1,571 @@
+package nicehttp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestResolvedURLDoesNotReceiveCredentials(t *testing.T) {
+	contents := testContents(64)
+
+	var logA, logB requestLog
+
+	b := newContentServer(t, contents, &logB)
+	a := newRedirectServer(t, b.URL+"/file", &logA)
+
+	c := newTestClient(8, WithBasicAuth("u", "p"))
+
+	buf, err := c.DownloadBytes(nil, a.URL+"/file")
+	if err != nil {
+		t.Fatalf("failed to download: %s", err)
+	}
+	if !bytes.Equal(buf, contents) {
+		t.Fatalf("downloaded contents do not match")
+	}
+
+	if reqs := logA.all(); len(reqs) != 1 || reqs[0].Authorization == "" {
+		t.Fatalf("expected the original host to receive a single request with credentials, got %+v", reqs)
+	}
+
+	var ranged int
+	for _, req := range logB.all() {
+		if req.Authorization != "" {
+			t.Fatalf("redirected-to host received credentials on %s request for range %q", req.Method, req.Range)
+		}
+		if req.Range != "" {
+			ranged++
+		}
+	}
+
+	if ranged != len(contents)/8 {
+		t.Fatalf("expected %d ranged requests to the redirected-to host, got %d", len(contents)/8, ranged)
+	}
+}
+
+func TestHeaderSetOnEveryRequest(t *testing.T) {
+	contents := testContents(64)
+
+	var log requestLog
+
+	srv := newContentServer(t, contents, &log)
+
+	c := newTestClient(8)
+	c.SetHeader("User-Agent", "nicehttp-test")
+	c.SetHeader("X-Custom", "value")
+
+	buf, err := c.DownloadBytes(nil, srv.URL)
+	if err != nil {
+		t.Fatalf("failed to download: %s", err)
+	}
+	if !bytes.Equal(buf, contents) {
+		t.Fatalf("downloaded contents do not match")
+	}
+
+	var head int
+
+	ranges := make(map[string]bool)
+
+	for _, req := range log.all() {
+		if got := req.Header.Get("User-Agent"); got != "nicehttp-test" {
+			t.Fatalf("%s request for range %q carried User-Agent %q", req.Method, req.Range, got)
+		}
+		if got := req.Header.Get("X-Custom"); got != "value" {
+			t.Fatalf("%s request for range %q carried X-Custom %q", req.Method, req.Range, got)
+		}
+
+		switch {
+		case req.Method == http.MethodHead:
+			head++
+		case req.Range != "":
+			ranges[req.Range] = true
+		}
+	}
+
+	if head == 0 {
+		t.Fatalf("expected the headers of the contents to be queried")
+	}
+
+	// The headers set on c are merged with the Range header set by every worker, rather than override it.
+
+	for start := 0; start < len(contents); start += 8 {
+		if r := fmt.Sprintf("bytes=%d-%d", start, start+7); !ranges[r] {
+			t.Fatalf("expected a request for range %q, got %v", r, ranges)
+		}
+	}
+}
+
+func TestChunkWorkersDoNotSendCredentialsToResolvedURL(t *testing.T) {
+	contents := testContents(64)
+
+	var logB requestLog
+
+	b := newContentServer(t, contents, &logB)
+	a := newRedirectServer(t, b.URL, nil)
+
+	c := newTestClient(8, WithBasicAuth("u", "p"))
+
+	buf := NewWriteBuffer(make([]byte, len(contents)))
+	if err := c.DownloadInChunks(buf, a.URL, len(contents)); err != nil {
+		t.Fatalf("failed to download: %s", err)
+	}
+
+	for _, req := range logB.all() {
+		if req.Authorization != "" {
+			t.Fatalf("redirected-to host received credentials on request for range %q", req.Range)
+		}
+	}
+}
+
+func TestRetryAfterSkipsRequestsWithBodyStream(t *testing.T) {
+	var calls int
+
+	c := WrapClient(transportFunc(func(req *fasthttp.Request, res *fasthttp.Response) error {
+		calls++
+
+		if calls == 1 {
+			res.SetStatusCode(fasthttp.StatusTooManyRequests)
+			res.Header.Set("Retry-After", "0")
+			return nil
+		}
+
+		res.SetStatusCode(fasthttp.StatusOK)
+		return nil
+	}))
+	c.RespectRetryAfter = true
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	res := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(res)
+
+	req.SetRequestURI("http://example.com/upload")
+	req.Header.SetMethod(fasthttp.MethodPost)
+	req.SetBodyStream(strings.NewReader("body"), -1)
+
+	if err := c.Do(req, res); err != nil {
+		t.Fatalf("failed to send request: %s", err)
+	}
+
+	if calls != 1 || res.StatusCode() != fasthttp.StatusTooManyRequests {
+		t.Fatalf("expected a request with a body stream to not be retried, got %d call(s) and status %d", calls, res.StatusCode())
+	}
+
+	calls = 0
+
+	req.SetBody([]byte("body"))
+	res.Reset()
+
+	if err := c.Do(req, res); err != nil {
+		t.Fatalf("failed to send request: %s", err)
+	}
+
+	if calls != 2 || res.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("expected a request with a body to be retried, got %d call(s) and status %d", calls, res.StatusCode())
+	}
+}
+
+func TestContentLengthProbe(t *testing.T) {
+	contents := testContents(64)
+
+	newServer := func(acceptsRanges bool, log *requestLog) *httptest.Server {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			log.record(r)
+
+			if r.Method == http.MethodHead {
+				if acceptsRanges {
+					w.Header().Set("Accept-Ranges", "bytes")
+				}
+				w.Header().Set("Transfer-Encoding", "chunked")
+				return
+			}
+
+			if acceptsRanges {
+				http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(contents))
+				return
+			}
+
+			w.Write(contents)
+		}))
+		t.Cleanup(srv.Close)
+
+		return srv
+	}
+
+	t.Run("probes the resolved URL", func(t *testing.T) {
+		var logA, logB requestLog
+
+		b := newServer(true, &logB)
+		a := newRedirectServer(t, b.URL, &logA)
+
+		c := newTestClient(8)
+
+		info, err := c.QueryInfo(a.URL)
+		if err != nil {
+			t.Fatalf("failed to query headers: %s", err)
+		}
+
+		if info.ContentLength != len(contents) || !info.AcceptsRanges {
+			t.Fatalf("expected the content length to be probed, got %+v", info)
+		}
+
+		if reqs := logA.all(); len(reqs) != 1 {
+			t.Fatalf("expected the probe to not follow the redirect again, got %d request(s) to the original host", len(reqs))
+		}
+	})
+
+	t.Run("skips servers that do not accept ranges", func(t *testing.T) {
+		var log requestLog
+
+		srv := newServer(false, &log)
+
+		c := newTestClient(8)
+
+		if _, err := c.QueryInfo(srv.URL); err != nil {
+			t.Fatalf("failed to query headers: %s", err)
+		}
+
+		for _, req := range log.all() {
+			if req.Method != http.MethodHead {
+				t.Fatalf("expected no probe to be sent, got a %s request for range %q", req.Method, req.Range)
+			}
+		}
+	})
+}
+
+func TestRetryOnNoFreeConns(t *testing.T) {
+	contents := testContents(64)
+
+	// Hold onto every connection for a while, such that workers beyond the one connection permitted find the pool
+	// exhausted.
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(contents))
+	}))
+	defer srv.Close()
+
+	var retries int32
+
+	c := newTestClient(8, WithNumWorkers(4), WithMaxConnsPerHost(1))
+	c.Backoff = ConstantBackoff(5 * time.Millisecond)
+	c.MaxNoFreeConnsRetries = 1000
+	c.OnRetry = func(req *fasthttp.Request, attempt int, err error) {
+		if errors.Is(err, fasthttp.ErrNoFreeConns) {
+			atomic.AddInt32(&retries, 1)
+		}
+	}
+
+	buf := NewWriteBuffer(make([]byte, len(contents)))
+	if err := c.DownloadInChunks(buf, srv.URL, len(contents)); err != nil {
+		t.Fatalf("failed to download: %s", err)
+	}
+	if !bytes.Equal(buf.Bytes(), contents) {
+		t.Fatalf("downloaded contents do not match")
+	}
+
+	if atomic.LoadInt32(&retries) == 0 {
+		t.Fatalf("expected requests to be retried on finding the connection pool exhausted")
+	}
+
+	c = newTestClient(8, WithNumWorkers(4), WithMaxConnsPerHost(1))
+	c.MaxNoFreeConnsRetries = 0
+
+	buf = NewWriteBuffer(make([]byte, len(contents)))
+	if err := c.DownloadInChunks(buf, srv.URL, len(contents)); !errors.Is(err, fasthttp.ErrNoFreeConns) {
+		t.Fatalf("expected download without retries to fail with ErrNoFreeConns, got %v", err)
+	}
+}
+
+func TestMaxRedirectCount(t *testing.T) {
+	// Redirect /0 to /1, /1 to /2, and /2 to /3, which responds with 200.
+
+	newClient := func(maxRedirectCount int, calls *int) Client {
+		c := WrapClient(transportFunc(func(req *fasthttp.Request, res *fasthttp.Response) error {
+			*calls++
+
+			if path := string(req.URI().Path()); path != "/3" {
+				res.SetStatusCode(fasthttp.StatusFound)
+				res.Header.Set("Location", fmt.Sprintf("/%d", path[1]-'0'+1))
+				return nil
+			}
+
+			res.SetStatusCode(fasthttp.StatusOK)
+			return nil
+		}))
+		c.MaxRedirectCount = maxRedirectCount
+		return c
+	}
+
+	do := func(c Client) (*fasthttp.Response, error) {
+		req := fasthttp.AcquireRequest()
+		defer fasthttp.ReleaseRequest(req)
+
+		res := fasthttp.AcquireResponse()
+
+		req.SetRequestURI("http://example.com/0")
+
+		return res, c.Do(req, res)
+	}
+
+	t.Run("zero returns the redirect response", func(t *testing.T) {
+		var calls int
+
+		res, err := do(newClient(0, &calls))
+		defer fasthttp.ReleaseResponse(res)
+
+		if err != nil {
+			t.Fatalf("failed to send request: %s", err)
+		}
+
+		if calls != 1 || res.StatusCode() != fasthttp.StatusFound || string(res.Header.Peek("Location")) != "/1" {
+			t.Fatalf("expected the first redirect response to be returned, got %d call(s) and status %d", calls, res.StatusCode())
+		}
+	})
+
+	t.Run("one fails on a chain of redirects", func(t *testing.T) {
+		var calls int
+
+		res, err := do(newClient(1, &calls))
+		defer fasthttp.ReleaseResponse(res)
+
+		var redirectErr *TooManyRedirectsError
+		if !errors.As(err, &redirectErr) {
+			t.Fatalf("expected a *TooManyRedirectsError, got %v", err)
+		}
+
+		if calls != 2 || len(redirectErr.Chain) != 2 {
+			t.Fatalf("expected a single redirect to be followed, got %d call(s) and chain %v", calls, redirectErr.Chain)
+		}
+	})
+
+	t.Run("negative follows every redirect", func(t *testing.T) {
+		var calls int
+
+		res, err := do(newClient(-1, &calls))
+		defer fasthttp.ReleaseResponse(res)
+
+		if err != nil {
+			t.Fatalf("failed to send request: %s", err)
+		}
+
+		if calls != 4 || res.StatusCode() != fasthttp.StatusOK {
+			t.Fatalf("expected all redirects to be followed, got %d call(s) and status %d", calls, res.StatusCode())
+		}
+	})
+}
+
+func TestRedirectRewritesMethodAndBody(t *testing.T) {
+	tests := []struct {
+		status int
+		method string
+		body   string
+	}{
+		{status: fasthttp.StatusMovedPermanently, method: fasthttp.MethodGet},
+		{status: fasthttp.StatusFound, method: fasthttp.MethodGet},
+		{status: fasthttp.StatusSeeOther, method: fasthttp.MethodGet},
+		{status: fasthttp.StatusTemporaryRedirect, method: fasthttp.MethodPost, body: "body"},
+		{status: fasthttp.StatusPermanentRedirect, method: fasthttp.MethodPost, body: "body"},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(fmt.Sprint(test.status), func(t *testing.T) {
+			var method, body, contentType string
+
+			c := WrapClient(transportFunc(func(req *fasthttp.Request, res *fasthttp.Response) error {
+				if string(req.URI().Path()) == "/upload" {
+					res.SetStatusCode(test.status)
+					res.Header.Set("Location", "/redirected")
+					return nil
+				}
+
+				method = string(req.Header.Method())
+				body = string(req.Body())
+				contentType = string(req.Header.ContentType())
+
+				res.SetStatusCode(fasthttp.StatusOK)
+				return nil
+			}))
+
+			req := fasthttp.AcquireRequest()
+			defer fasthttp.ReleaseRequest(req)
+
+			res := fasthttp.AcquireResponse()
+			defer fasthttp.ReleaseResponse(res)
+
+			req.SetRequestURI("http://example.com/upload")
+			req.Header.SetMethod(fasthttp.MethodPost)
+			req.Header.SetContentType("text/plain")
+			req.SetBodyString("body")
+
+			if err := c.Do(req, res); err != nil {
+				t.Fatalf("failed to send request: %s", err)
+			}
+
+			if method != test.method || body != test.body {
+				t.Fatalf("expected redirected request to be %s with body %q, got %s with body %q", test.method, test.body, method, body)
+			}
+
+			if test.body == "" && contentType != "" {
+				t.Fatalf("expected redirected request without a body to have no Content-Type, got %q", contentType)
+			}
+		})
+	}
+}
+
+func TestDownloadFileClosesFile(t *testing.T) {
+	if _, err := os.Stat("/proc/self/fd"); err != nil {
+		t.Skip("open file descriptors may only be counted through /proc/self/fd")
+	}
+
+	contents := testContents(64)
+
+	srv := newContentServer(t, contents, nil)
+
+	dir, err := ioutil.TempDir("", "nicehttp")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	countFDs := func() int {
+		fds, err := ioutil.ReadDir("/proc/self/fd")
+		if err != nil {
+			t.Fatalf("failed to count open file descriptors: %s", err)
+		}
+		return len(fds)
+	}
+
+	for _, acceptsRanges := range []bool{false, true} {
+		c := newTestClient(8)
+		c.AcceptsRanges = acceptsRanges
+
+		// Download once beforehand, such that connections to the test server are already open.
+
+		if err := c.DownloadFile(filepath.Join(dir, "file"), srv.URL); err != nil {
+			t.Fatalf("failed to download: %s", err)
+		}
+
+		before := countFDs()
+
+		for i := 0; i < 32; i++ {
+			if err := c.DownloadFile(filepath.Join(dir, fmt.Sprintf("file-%t-%d", acceptsRanges, i)), srv.URL); err != nil {
+				t.Fatalf("failed to download: %s", err)
+			}
+		}
+
+		// Allow for a few connections to be opened or closed in the meantime.
+
+		if after := countFDs(); after > before+4 {
+			t.Fatalf("expected files to be closed once downloaded, went from %d to %d open file descriptors", before, after)
+		}
+	}
+}
+
+func TestSameHostRedirectsOnly(t *testing.T) {
+	tests := []struct {
+		name     string
+		location string
+		follow   bool
+	}{
+		{name: "path change", location: "http://example.com/moved", follow: true},
+		{name: "scheme upgrade", location: "https://example.com/", follow: true},
+		{name: "different host", location: "http://example.org/", follow: false},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			var followed []string
+
+			c := WrapClient(transportFunc(func(req *fasthttp.Request, res *fasthttp.Response) error {
+				if string(req.URI().Path()) == "/start" {
+					res.SetStatusCode(fasthttp.StatusFound)
+					res.Header.Set("Location", test.location)
+					return nil
+				}
+
+				followed = append(followed, req.URI().String())
+
+				res.SetStatusCode(fasthttp.StatusOK)
+				return nil
+			}))
+			c.SameHostRedirectsOnly = true
+
+			req := fasthttp.AcquireRequest()
+			defer fasthttp.ReleaseRequest(req)
+
+			res := fasthttp.AcquireResponse()
+			defer fasthttp.ReleaseResponse(res)
+
+			req.SetRequestURI("http://example.com/start")
+
+			err := c.Do(req, res)
+
+			if !test.follow {
+				if err == nil || len(followed) != 0 {
+					t.Fatalf("expected redirect to %q to be refused, got %v and requests to %v", test.location, err, followed)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("failed to send request: %s", err)
+			}
+
+			if len(followed) != 1 || followed[0] != test.location {
+				t.Fatalf("expected redirect to %q to be followed, got requests to %v", test.location, followed)
+			}
+		})
+	}
+}
+
+func TestMaxEgressBytes(t *testing.T) {
+	contents := testContents(64)
+
+	srv := newContentServer(t, contents, nil)
+
+	// Requests are refused once more bytes than the budget have been transferred, such that two downloads fit within
+	// it, but not a third.
+
+	c := newTestClient(8)
+	c.MaxEgressBytes = 2*int64(len(contents)) - 1
+
+	// Bytes transferred by every download made by c count towards its budget, and clones of c share its budget.
+
+	if _, err := c.DownloadBytes(nil, srv.URL); err != nil {
+		t.Fatalf("failed to download: %s", err)
+	}
+
+	if n := c.TransferredBytes(); n != int64(len(contents)) {
+		t.Fatalf("expected %d byte(s) to have been transferred, got %d", len(contents), n)
+	}
+
+	clone := c.Clone()
+
+	if _, err := clone.DownloadBytes(nil, srv.URL); err != nil {
+		t.Fatalf("failed to download: %s", err)
+	}
+
+	if n := c.TransferredBytes(); n != 2*int64(len(contents)) {
+		t.Fatalf("expected %d byte(s) to have been transferred, got %d", 2*len(contents), n)
+	}
+
+	if _, err := c.DownloadBytes(nil, srv.URL); !errors.Is(err, ErrEgressBudgetExceeded) {
+		t.Fatalf("expected ErrEgressBudgetExceeded, got %v", err)
+	}
+}
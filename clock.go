@@ -0,0 +1,44 @@
+package nicehttp
+
+import "time"
+
+// Clock is the source of the current time of a Client, which is also used to wait out backoff and throttling delays.
+// It may be replaced using WithClock, i.e. such that timeouts, deadlines, retries and rate limits may be tested
+// against a fake clock which is advanced by hand rather than by waiting.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After returns a channel which receives the current time once d has passed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// clock returns the current time as reported by the Clock of c, or by time.Now should c have no Clock.
+func (c *Client) clock() time.Time {
+	if c.clk != nil {
+		return c.clk.Now()
+	}
+	return time.Now()
+}
+
+// sleep blocks until d has passed as reported by the Clock of c, or by the system clock should c have no Clock. It
+// returns ErrCanceled early should the download in progress be canceled beforehand.
+func (c *Client) sleep(d time.Duration) error {
+	var after <-chan time.Time
+
+	if c.clk != nil {
+		after = c.clk.After(d)
+	} else {
+		t := time.NewTimer(d)
+		defer t.Stop()
+
+		after = t.C
+	}
+
+	select {
+	case <-after:
+		return nil
+	case <-c.canceled:
+		return ErrCanceled
+	}
+}
@@ -0,0 +1,135 @@
+package nicehttp
+
+import (
+	"bytes"
+	"github.com/valyala/fasthttp"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock which only advances once it is waited on, such that delays are waited out instantly.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	ch <- c.Advance(d)
+	return ch
+}
+
+func (c *fakeClock) Advance(d time.Duration) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	return c.now
+}
+
+func TestClockWaitsOutRetryAfter(t *testing.T) {
+	clock := newFakeClock()
+	start := clock.Now()
+
+	var calls int
+
+	c := WrapClient(transportFunc(func(req *fasthttp.Request, res *fasthttp.Response) error {
+		if calls++; calls == 1 {
+			res.SetStatusCode(fasthttp.StatusServiceUnavailable)
+			res.Header.Set("Retry-After", "3600")
+			return nil
+		}
+
+		res.SetStatusCode(fasthttp.StatusOK)
+		return nil
+	}))
+	c.RespectRetryAfter = true
+
+	WithClock(clock)(&c)
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	res := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(res)
+
+	req.SetRequestURI("http://example.com/")
+
+	done := make(chan error, 1)
+	go func() { done <- c.Do(req, res) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("failed to send request: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the Retry-After delay to be waited out against the clock")
+	}
+
+	if calls != 2 || res.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("expected the request to be retried once, got %d call(s) and status %d", calls, res.StatusCode())
+	}
+
+	if waited := clock.Now().Sub(start); waited != time.Hour {
+		t.Fatalf("expected the clock to have advanced by %s, got %s", time.Hour, waited)
+	}
+}
+
+func TestClockWaitsOutThrottling(t *testing.T) {
+	contents := testContents(64)
+
+	srv := newContentServer(t, contents, nil)
+
+	clock := newFakeClock()
+	start := clock.Now()
+
+	c := newTestClient(8, WithClock(clock))
+	c.AcceptsRanges = false
+	c.MaxBytesPerSecond = 8
+
+	buf, err := c.DownloadBytes(nil, srv.URL)
+	if err != nil {
+		t.Fatalf("failed to download: %s", err)
+	}
+	if !bytes.Equal(buf, contents) {
+		t.Fatalf("downloaded contents do not match")
+	}
+
+	// The first second worth of bytes is available up front, such that the remaining 56 bytes take 7 seconds.
+
+	if waited := clock.Now().Sub(start); waited != 7*time.Second {
+		t.Fatalf("expected the clock to have advanced by %s, got %s", 7*time.Second, waited)
+	}
+}
+
+func TestHostCircuitBreakerClock(t *testing.T) {
+	clock := newFakeClock()
+
+	b := NewHostCircuitBreaker(1, 0, time.Minute)
+	b.Clock = clock
+
+	b.Report("example.com", true)
+
+	if b.Allow("example.com") {
+		t.Fatalf("expected the circuit to be open")
+	}
+
+	clock.Advance(time.Minute)
+
+	if !b.Allow("example.com") {
+		t.Fatalf("expected a probe to be let through once the cooldown has passed")
+	}
+}
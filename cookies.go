@@ -0,0 +1,64 @@
+package nicehttp
+
+import (
+	"github.com/valyala/fasthttp"
+	"net/http"
+	"net/url"
+)
+
+// setCookies attaches to req all cookies in c.Jar that apply to the URI of req. The cookies named by prev, which were
+// attached to req by a previous call to setCookies, are removed from req first. It returns the names of all cookies
+// that were attached to req.
+func (c *Client) setCookies(req *fasthttp.Request, prev []string) []string {
+	for _, name := range prev {
+		req.Header.DelCookie(name)
+	}
+
+	if c.Jar == nil {
+		return nil
+	}
+
+	u, err := url.Parse(req.URI().String())
+	if err != nil {
+		return nil
+	}
+
+	cookies := c.Jar.Cookies(u)
+	names := make([]string, 0, len(cookies))
+
+	for _, cookie := range cookies {
+		req.Header.SetCookie(cookie.Name, cookie.Value)
+		names = append(names, cookie.Name)
+	}
+
+	return names
+}
+
+// saveCookies stores all cookies set by res into c.Jar, scoped to the URI of req.
+func (c *Client) saveCookies(req *fasthttp.Request, res *fasthttp.Response) {
+	if c.Jar == nil {
+		return
+	}
+
+	header := make(http.Header)
+
+	res.Header.VisitAllCookie(func(_, value []byte) {
+		header.Add("Set-Cookie", string(value))
+	})
+
+	if len(header) == 0 {
+		return
+	}
+
+	u, err := url.Parse(req.URI().String())
+	if err != nil {
+		return
+	}
+
+	cookies := (&http.Response{Header: header}).Cookies()
+	if len(cookies) == 0 {
+		return
+	}
+
+	c.Jar.SetCookies(u, cookies)
+}
@@ -0,0 +1,160 @@
+package nicehttp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+// ErrDecompressedTooLarge is returned should the decompressed body of a response exceed Client.MaxDecompressedSize.
+var ErrDecompressedTooLarge = errors.New("decompressed body too large")
+
+// Decompressor wraps r, which reads contents encoded using a content encoding, into a reader of the decoded contents.
+type Decompressor func(r io.Reader) (io.ReadCloser, error)
+
+var (
+	decompressorsMu sync.RWMutex
+	decompressors   = map[string]Decompressor{
+		"gzip":    func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+		"x-gzip":  func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+		"deflate": zlib.NewReader,
+	}
+)
+
+// RegisterDecompressor registers decompress as the Decompressor of contents encoded using encoding, as reported by
+// the Content-Encoding header of a response. It replaces any Decompressor previously registered for encoding. gzip
+// and deflate are registered by default. Other encodings such as br or zstd may be registered using third-party
+// packages, i.e:
+//
+//	nicehttp.RegisterDecompressor("br", func(r io.Reader) (io.ReadCloser, error) {
+//		return ioutil.NopCloser(brotli.NewReader(r)), nil
+//	})
+func RegisterDecompressor(encoding string, decompress Decompressor) {
+	decompressorsMu.Lock()
+	defer decompressorsMu.Unlock()
+
+	decompressors[strings.ToLower(encoding)] = decompress
+}
+
+// decompressor returns the Decompressor registered for encoding, if any.
+func decompressor(encoding string) (Decompressor, bool) {
+	decompressorsMu.RLock()
+	defer decompressorsMu.RUnlock()
+
+	decompress, ok := decompressors[encoding]
+	return decompress, ok
+}
+
+// contentEncoding returns the normalized value of the Content-Encoding header of res. It returns an empty string
+// should the contents of res not be encoded.
+func contentEncoding(header *fasthttp.ResponseHeader) string {
+	encoding := strings.ToLower(string(bytes.TrimSpace(header.Peek("Content-Encoding"))))
+	if encoding == "identity" {
+		return ""
+	}
+	return encoding
+}
+
+// advertisesEncoding reports whether or not c advertises accepting any content encoding other than identity through
+// c.AcceptEncoding.
+func (c *Client) advertisesEncoding() bool {
+	encoding := strings.ToLower(strings.TrimSpace(c.AcceptEncoding))
+	return encoding != "" && encoding != "identity"
+}
+
+// writeBody writes the body of res to w, transparently decompressing it should it be encoded using an encoding that
+// a Decompressor is registered for and c.DisableDecompression not be set. It returns the number of bytes written to w.
+// Bodies encoded using an encoding that no Decompressor is registered for are written as-is.
+func (c *Client) writeBody(w io.Writer, res *fasthttp.Response) (int64, error) {
+	body, err := c.bodyReader(res)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	buf := acquireCopyBuffer(c.CopyBufferSize)
+	defer releaseCopyBuffer(buf)
+
+	// Hide any io.WriterTo and io.ReaderFrom implemented by body and w, which io.CopyBuffer would otherwise use to
+	// bypass buf.
+
+	return io.CopyBuffer(struct{ io.Writer }{w}, struct{ io.Reader }{body}, *buf)
+}
+
+// decodedLength returns the number of bytes that the body of res is written as by writeBody, which is length should
+// it be positive, and the Content-Length of res otherwise. It returns zero should the number of bytes not be known,
+// i.e. should the body be decompressed as it is written.
+func (c *Client) decodedLength(res *fasthttp.Response, length int) int64 {
+	if !c.DisableDecompression && contentEncoding(&res.Header) != "" {
+		return 0
+	}
+	if length <= 0 {
+		length = res.Header.ContentLength()
+	}
+	if length < 0 {
+		return 0
+	}
+	return int64(length)
+}
+
+// bodyReader returns a reader of the body of res, which transparently decompresses it the same way writeBody does.
+// The reader must be closed once it is no longer needed.
+func (c *Client) bodyReader(res *fasthttp.Response) (io.ReadCloser, error) {
+	body := ioutil.NopCloser(bytes.NewReader(res.Body()))
+
+	encoding := contentEncoding(&res.Header)
+	if c.DisableDecompression || encoding == "" {
+		return body, nil
+	}
+
+	if decompress, ok := decompressor(encoding); ok {
+		r, err := decompress(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress %s body: %w", encoding, err)
+		}
+
+		body = r
+	}
+
+	if c.MaxDecompressedSize > 0 {
+		body = &decompressionLimiter{ReadCloser: body, remaining: c.MaxDecompressedSize}
+	}
+
+	return body, nil
+}
+
+// decompressionLimiter reads from a decompressor, and fails with ErrDecompressedTooLarge once more than a given number
+// of bytes have been read from it.
+type decompressionLimiter struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (l *decompressionLimiter) Read(b []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, ErrDecompressedTooLarge
+	}
+
+	// Read one byte past the limit, such that decompressed contents that are exactly as large as the limit are not
+	// mistaken for contents that exceed it.
+
+	if int64(len(b)) > l.remaining+1 {
+		b = b[:l.remaining+1]
+	}
+
+	n, err := l.ReadCloser.Read(b)
+	l.remaining -= int64(n)
+
+	if l.remaining < 0 {
+		return n + int(l.remaining), ErrDecompressedTooLarge
+	}
+
+	return n, err
+}
@@ -0,0 +1,88 @@
+package nicehttp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDownloadSeriallyDecompresses(t *testing.T) {
+	contents := testContents(1024)
+
+	encoders := map[string]func(w io.Writer) io.WriteCloser{
+		"gzip": func(w io.Writer) io.WriteCloser {
+			return gzip.NewWriter(w)
+		},
+		"deflate": func(w io.Writer) io.WriteCloser {
+			return zlib.NewWriter(w)
+		},
+	}
+
+	for encoding, newEncoder := range encoders {
+		encoding := encoding
+
+		var encoded bytes.Buffer
+
+		zw := newEncoder(&encoded)
+		if _, err := zw.Write(contents); err != nil {
+			t.Fatalf("failed to compress contents: %s", err)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatalf("failed to compress contents: %s", err)
+		}
+
+		t.Run(encoding, func(t *testing.T) {
+			var log requestLog
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				log.record(r)
+
+				w.Header().Set("Accept-Ranges", "bytes")
+				w.Header().Set("Content-Encoding", encoding)
+				w.Write(encoded.Bytes())
+			}))
+			defer srv.Close()
+
+			c := newTestClient(8)
+
+			var buf bytes.Buffer
+			if err := c.DownloadSerially(&buf, srv.URL); err != nil {
+				t.Fatalf("failed to download: %s", err)
+			}
+			if !bytes.Equal(buf.Bytes(), contents) {
+				t.Fatalf("expected contents to be decompressed")
+			}
+
+			// The content length of encoded contents is that of the encoded contents, such that they may not be
+			// downloaded in chunks even though the server accepts ranges.
+
+			dst, err := c.DownloadBytes(nil, srv.URL)
+			if err != nil {
+				t.Fatalf("failed to download: %s", err)
+			}
+			if !bytes.Equal(dst, contents) {
+				t.Fatalf("expected contents to be decompressed")
+			}
+
+			for _, req := range log.all() {
+				if req.Range != "" {
+					t.Fatalf("expected encoded contents to be downloaded serially, got a request for range %q", req.Range)
+				}
+			}
+
+			c.DisableDecompression = true
+
+			buf.Reset()
+			if err := c.DownloadSerially(&buf, srv.URL); err != nil {
+				t.Fatalf("failed to download: %s", err)
+			}
+			if !bytes.Equal(buf.Bytes(), encoded.Bytes()) {
+				t.Fatalf("expected contents to be left as-is with DisableDecompression set")
+			}
+		})
+	}
+}
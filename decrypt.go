@@ -0,0 +1,133 @@
+package nicehttp
+
+import (
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var _ io.WriteCloser = (*cbcDecryptWriter)(nil)
+
+// DownloadDecrypt serially downloads the contents of url, decrypts them using block in CTR mode with initialization
+// vector iv as they are written, and writes the plaintext to w.
+//
+// CTR mode derives its keystream from a counter, such that any byte range of the ciphertext may in theory be decrypted
+// independently given its offset. Nonetheless, the contents of url are downloaded serially.
+func (c *Client) DownloadDecrypt(w io.Writer, url string, block cipher.Block, iv []byte) error {
+	return c.DownloadDecryptDeadline(w, url, block, iv, zeroTime)
+}
+
+// DownloadDecryptTimeout serially downloads the contents of url, decrypts them using block in CTR mode with
+// initialization vector iv as they are written, and writes the plaintext to w.
+func (c *Client) DownloadDecryptTimeout(w io.Writer, url string, block cipher.Block, iv []byte, timeout time.Duration) error {
+	return c.DownloadDecryptDeadline(w, url, block, iv, c.clock().Add(timeout))
+}
+
+// DownloadDecryptDeadline serially downloads the contents of url, decrypts them using block in CTR mode with
+// initialization vector iv as they are written, and writes the plaintext to w.
+func (c *Client) DownloadDecryptDeadline(w io.Writer, url string, block cipher.Block, iv []byte, deadline time.Time) error {
+	if len(iv) != block.BlockSize() {
+		return fmt.Errorf("iv is %d byte(s), but must be %d byte(s)", len(iv), block.BlockSize())
+	}
+
+	return c.DownloadSeriallyDeadline(cipher.StreamWriter{S: cipher.NewCTR(block, iv), W: w}, url, deadline)
+}
+
+// DownloadDecryptCBC serially downloads the contents of url, decrypts them using block in CBC mode with initialization
+// vector iv as they are written, and writes the plaintext to w.
+//
+// CBC mode requires each block to be decrypted in order, and so may only ever be used to decrypt downloads serially.
+// The plaintext is expected to have been padded using PKCS #7 padding, which is stripped away once the final block
+// has been decrypted.
+func (c *Client) DownloadDecryptCBC(w io.Writer, url string, block cipher.Block, iv []byte) error {
+	return c.DownloadDecryptCBCDeadline(w, url, block, iv, zeroTime)
+}
+
+// DownloadDecryptCBCTimeout serially downloads the contents of url, decrypts them using block in CBC mode with
+// initialization vector iv as they are written, and writes the plaintext to w.
+func (c *Client) DownloadDecryptCBCTimeout(w io.Writer, url string, block cipher.Block, iv []byte, timeout time.Duration) error {
+	return c.DownloadDecryptCBCDeadline(w, url, block, iv, c.clock().Add(timeout))
+}
+
+// DownloadDecryptCBCDeadline serially downloads the contents of url, decrypts them using block in CBC mode with
+// initialization vector iv as they are written, and writes the plaintext to w.
+func (c *Client) DownloadDecryptCBCDeadline(w io.Writer, url string, block cipher.Block, iv []byte, deadline time.Time) error {
+	if len(iv) != block.BlockSize() {
+		return fmt.Errorf("iv is %d byte(s), but must be %d byte(s)", len(iv), block.BlockSize())
+	}
+
+	dw := &cbcDecryptWriter{dst: w, mode: cipher.NewCBCDecrypter(block, iv)}
+
+	if err := c.DownloadSeriallyDeadline(dw, url, deadline); err != nil {
+		return err
+	}
+
+	return dw.Close()
+}
+
+// cbcDecryptWriter implements io.WriteCloser, decrypting ciphertext written to it in CBC mode and writing the
+// plaintext to an underlying io.Writer. The last block decrypted is held back until Close is called, upon which its
+// PKCS #7 padding is stripped away.
+type cbcDecryptWriter struct {
+	dst  io.Writer
+	mode cipher.BlockMode
+	buf  []byte
+	last []byte
+}
+
+// Write implements io.Writer.
+func (w *cbcDecryptWriter) Write(b []byte) (int, error) {
+	size := w.mode.BlockSize()
+
+	w.buf = append(w.buf, b...)
+
+	n := len(w.buf) / size * size
+	if n == 0 {
+		return len(b), nil
+	}
+
+	plaintext := make([]byte, n)
+	w.mode.CryptBlocks(plaintext, w.buf[:n])
+	w.buf = append(w.buf[:0], w.buf[n:]...)
+
+	if len(w.last) > 0 {
+		if _, err := w.dst.Write(w.last); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err := w.dst.Write(plaintext[:n-size]); err != nil {
+		return 0, err
+	}
+
+	w.last = plaintext[n-size:]
+
+	return len(b), nil
+}
+
+// Close strips away the PKCS #7 padding of the last block, and writes it.
+func (w *cbcDecryptWriter) Close() error {
+	if len(w.buf) > 0 {
+		return errors.New("ciphertext is not a multiple of the block size")
+	}
+
+	if len(w.last) == 0 {
+		return errors.New("ciphertext is empty")
+	}
+
+	pad := int(w.last[len(w.last)-1])
+	if pad == 0 || pad > len(w.last) {
+		return errors.New("invalid pkcs #7 padding")
+	}
+
+	for _, b := range w.last[len(w.last)-pad:] {
+		if int(b) != pad {
+			return errors.New("invalid pkcs #7 padding")
+		}
+	}
+
+	_, err := w.dst.Write(w.last[:len(w.last)-pad])
+	return err
+}
@@ -0,0 +1,80 @@
+package nicehttp
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"testing"
+)
+
+// Test vectors for AES-128 from NIST SP 800-38A, F.2.1 (CBC) and F.5.1 (CTR).
+const (
+	aesTestKey       = "2b7e151628aed2a6abf7158809cf4f3c"
+	aesTestPlaintext = "6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e51" +
+		"30c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710"
+
+	aesTestCBCIV         = "000102030405060708090a0b0c0d0e0f"
+	aesTestCBCCiphertext = "7649abac8119b246cee98e9b12e9197d5086cb9b507219ee95db113a917678b2" +
+		"73bed6b8e3c1743b7116e69e222295163ff1caa1681fac09120eca307586e1a7"
+
+	aesTestCTRIV         = "f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff"
+	aesTestCTRCiphertext = "874d6191b620e3261bef6864990db6ce9806f66b7970fdff8617187bb9fffdff" +
+		"5ae4df3edbd5d35e5b4f09020db03eab1e031dda2fbe03d1792170a0f3009cee"
+)
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("failed to decode %q: %s", s, err)
+	}
+	return b
+}
+
+func TestDownloadDecrypt(t *testing.T) {
+	block, err := aes.NewCipher(mustDecodeHex(t, aesTestKey))
+	if err != nil {
+		t.Fatalf("failed to create cipher: %s", err)
+	}
+
+	plaintext := mustDecodeHex(t, aesTestPlaintext)
+
+	// Copy the body in pieces that do not line up with the block size of AES.
+
+	c := newTestClient(8, WithCopyBufferSize(5))
+
+	t.Run("CTR", func(t *testing.T) {
+		srv := newContentServer(t, mustDecodeHex(t, aesTestCTRCiphertext), nil)
+
+		var buf bytes.Buffer
+		if err := c.DownloadDecrypt(&buf, srv.URL, block, mustDecodeHex(t, aesTestCTRIV)); err != nil {
+			t.Fatalf("failed to download: %s", err)
+		}
+
+		if !bytes.Equal(buf.Bytes(), plaintext) {
+			t.Fatalf("expected plaintext %x, got %x", plaintext, buf.Bytes())
+		}
+	})
+
+	t.Run("CBC", func(t *testing.T) {
+		ciphertext := mustDecodeHex(t, aesTestCBCCiphertext)
+
+		// The test vectors are not padded, so chain a block of PKCS #7 padding onto the end of the ciphertext.
+
+		padding := bytes.Repeat([]byte{aes.BlockSize}, aes.BlockSize)
+		cipher.NewCBCEncrypter(block, ciphertext[len(ciphertext)-aes.BlockSize:]).CryptBlocks(padding, padding)
+
+		srv := newContentServer(t, append(ciphertext, padding...), nil)
+
+		var buf bytes.Buffer
+		if err := c.DownloadDecryptCBC(&buf, srv.URL, block, mustDecodeHex(t, aesTestCBCIV)); err != nil {
+			t.Fatalf("failed to download: %s", err)
+		}
+
+		if !bytes.Equal(buf.Bytes(), plaintext) {
+			t.Fatalf("expected plaintext %x, got %x", plaintext, buf.Bytes())
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package nicehttp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrCanceled is returned by a download that was canceled using DownloadHandle.Cancel.
+var ErrCanceled = errors.New("download canceled")
+
+// DownloadHandle is a handle to a file download running in the background, which may be canceled from another
+// goroutine without tearing down the Client it was started from.
+type DownloadHandle struct {
+	canceled   chan struct{}
+	cancelOnce sync.Once
+
+	done chan struct{}
+
+	n   int64
+	err error
+}
+
+// Cancel stops the download. Workers downloading chunks stop as soon as they are canceled, while a serial download
+// stops once the request it is waiting on completes. Backoff delays between retries and delays throttling writes are
+// cut short by Cancel as well. The partial file of the download is removed unless
+// KeepPartialFiles is set. Cancel is safe to call more than once, and is a no-op once the download has completed.
+func (d *DownloadHandle) Cancel() {
+	d.cancelOnce.Do(func() { close(d.canceled) })
+}
+
+// Wait blocks until the download completes, and returns the number of bytes of its contents that were written, and
+// the error it failed with, if any. Should the download have been canceled, the error satisfies
+// errors.Is(err, ErrCanceled).
+func (d *DownloadHandle) Wait() (int64, error) {
+	<-d.done
+	return d.n, d.err
+}
+
+// StartDownloadFile starts downloading the contents of url into a newly-created file titled filename in the
+// background, and returns a handle to the download which may be used to cancel it, or to wait for it to complete.
+func (c *Client) StartDownloadFile(filename, url string, opts ...DownloadOption) *DownloadHandle {
+	return c.StartDownloadFileDeadline(filename, url, zeroTime, opts...)
+}
+
+// StartDownloadFileTimeout starts downloading the contents of url into a newly-created file titled filename in the
+// background, and returns a handle to the download.
+func (c *Client) StartDownloadFileTimeout(filename, url string, timeout time.Duration, opts ...DownloadOption) *DownloadHandle {
+	return c.StartDownloadFileDeadline(filename, url, c.clock().Add(timeout), opts...)
+}
+
+// StartDownloadFileDeadline starts downloading the contents of url into a newly-created file titled filename in the
+// background, and returns a handle to the download.
+func (c *Client) StartDownloadFileDeadline(filename, url string, deadline time.Time, opts ...DownloadOption) *DownloadHandle {
+	d := &DownloadHandle{canceled: make(chan struct{}), done: make(chan struct{})}
+
+	cc, written := c.countWritten()
+	cc.canceled = d.canceled
+
+	go func() {
+		defer close(d.done)
+
+		d.err = cc.DownloadFileDeadline(filename, url, deadline, opts...)
+		d.n = atomic.LoadInt64(written)
+	}()
+
+	return d
+}
+
+// wasCanceled reports whether or not the download in progress has been canceled using DownloadHandle.Cancel.
+func (c *Client) wasCanceled() bool {
+	select {
+	case <-c.canceled:
+		return true
+	default:
+		return false
+	}
+}
+
+// cancelable returns a copy of ctx which is canceled once the download in progress is canceled using
+// DownloadHandle.Cancel. It returns ctx as-is should the download not be cancelable.
+func (c *Client) cancelable(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.canceled == nil {
+		return ctx, func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	go func() {
+		select {
+		case <-c.canceled:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
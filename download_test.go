@@ -0,0 +1,90 @@
+package nicehttp
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCancelInterruptsDelays(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nicehttp")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// wait starts a download from srv, cancels it once srv has received a request, and checks that the download
+	// returns ErrCanceled without waiting out its delay.
+
+	wait := func(t *testing.T, c Client, srv *httptest.Server, requested <-chan struct{}) {
+		d := c.StartDownloadFile(filepath.Join(dir, filepath.Base(t.Name())), srv.URL)
+
+		select {
+		case <-requested:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected a request to be sent")
+		}
+
+		d.Cancel()
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := d.Wait()
+			done <- err
+		}()
+
+		select {
+		case err := <-done:
+			if !errors.Is(err, ErrCanceled) {
+				t.Fatalf("expected ErrCanceled, got %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected the download to stop without waiting out its delay")
+		}
+	}
+
+	t.Run("backoff", func(t *testing.T) {
+		requested := make(chan struct{}, 1)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case requested <- struct{}{}:
+			default:
+			}
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+
+		c := newTestClient(8)
+		c.RespectRetryAfter = true
+		c.MaxRetries = 1
+
+		wait(t, c, srv, requested)
+	})
+
+	t.Run("throttle", func(t *testing.T) {
+		requested := make(chan struct{}, 1)
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				select {
+				case requested <- struct{}{}:
+				default:
+				}
+			}
+			w.Write(testContents(64))
+		}))
+		defer srv.Close()
+
+		c := newTestClient(8)
+		c.MaxBytesPerSecond = 1
+
+		wait(t, c, srv, requested)
+	})
+}
@@ -0,0 +1,80 @@
+package nicehttp
+
+import (
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"strings"
+)
+
+// maxHTTPErrorBodySize is the max number of bytes of a response body that are kept in a *HTTPError.
+const maxHTTPErrorBodySize = 1024
+
+// HTTPError is returned by downloads should c.CheckStatus be set, and a response have a status code of 400 or above.
+// It is always returned by DownloadJSON should a response not have a 2xx status code.
+// Body holds at most the first 1 KiB of the body of the response.
+type HTTPError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("unexpected status code %d (%s): %q", e.StatusCode, fasthttp.StatusMessage(e.StatusCode), e.Body)
+}
+
+// checkStatus returns a *HTTPError should c.CheckStatus be set, and res have a status code of 400 or above.
+func (c *Client) checkStatus(res *fasthttp.Response) error {
+	if !c.CheckStatus || res.StatusCode() < fasthttp.StatusBadRequest {
+		return nil
+	}
+	return newHTTPError(res)
+}
+
+// newHTTPError returns a *HTTPError describing the status code and body of res.
+func newHTTPError(res *fasthttp.Response) *HTTPError {
+	body := res.Body()
+	if len(body) > maxHTTPErrorBodySize {
+		body = body[:maxHTTPErrorBodySize]
+	}
+
+	return &HTTPError{StatusCode: res.StatusCode(), Body: append([]byte(nil), body...)}
+}
+
+// NetworkError is returned should the Transport of a Client fail to send a request or to receive its response, i.e.
+// as a result of a failure to resolve, dial or handshake with a host, a connection being reset, or a timeout. It sets
+// failures of the network apart from a *HTTPError, which is returned should a response have been received but have an
+// unexpected status code. Err is the error returned by the Transport, such that errors.Is(err, fasthttp.ErrTimeout)
+// holds for a NetworkError caused by a timeout. Both may be retrieved using errors.As from the errors returned by
+// downloads, including those of workers wrapped in a *ChunkError.
+type NetworkError struct {
+	URL string
+	Err error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("failed to send request to %q: %s", e.URL, e.Err)
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// TooManyRedirectsError is returned should a request be redirected more than Client.MaxRedirectCount times. Chain
+// lists the URLs that were requested and redirected from, in order, such that a redirect loop may be told apart.
+type TooManyRedirectsError struct {
+	Chain []string
+}
+
+func (e *TooManyRedirectsError) Error() string {
+	return fmt.Sprintf("redirected too many times: %s", strings.Join(e.Chain, " -> "))
+}
+
+// MissingLocationError is returned should a request be responded to with a redirect status code, but without a
+// Location header to redirect to.
+type MissingLocationError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *MissingLocationError) Error() string {
+	return fmt.Sprintf("missing 'Location' header after redirect from %q with status code %d", e.URL, e.StatusCode)
+}
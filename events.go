@@ -0,0 +1,128 @@
+package nicehttp
+
+import (
+	"io"
+	"sync"
+)
+
+var (
+	_ DownloadEvent = Started{}
+	_ DownloadEvent = Progress{}
+	_ DownloadEvent = ChunkDone{}
+	_ DownloadEvent = Retry{}
+	_ DownloadEvent = Completed{}
+	_ DownloadEvent = Failed{}
+)
+
+// downloadEventBufferSize is the number of events that may be buffered before the oldest ones start getting dropped.
+const downloadEventBufferSize = 64
+
+// DownloadEvent is an event emitted while a download is in progress. It is either of Started, Progress, ChunkDone,
+// Retry, Completed, or Failed.
+type DownloadEvent interface {
+	downloadEvent()
+}
+
+// Started is emitted once the headers of a URL have been queried, and its contents are about to be downloaded.
+type Started struct {
+	URL           string
+	ContentLength int
+	Chunked       bool
+}
+
+// Progress is emitted every time a portion of the contents of a URL have been written. Total is zero should the
+// content length of the URL not be known.
+type Progress struct {
+	Done  int64
+	Total int64
+}
+
+// ChunkDone is emitted every time a byte range of a URL has been downloaded and written.
+type ChunkDone struct {
+	Start int
+	End   int
+}
+
+// Retry is emitted every time a failed request is about to be attempted again.
+type Retry struct {
+	Attempt int
+	Err     error
+}
+
+// Completed is emitted once a download has successfully completed.
+type Completed struct{}
+
+// Failed is emitted once a download has failed.
+type Failed struct {
+	Err error
+}
+
+func (Started) downloadEvent()   {}
+func (Progress) downloadEvent()  {}
+func (ChunkDone) downloadEvent() {}
+func (Retry) downloadEvent()     {}
+func (Completed) downloadEvent() {}
+func (Failed) downloadEvent()    {}
+
+// progressWriter wraps w such that a Progress event is emitted every time bytes are written to it, reporting the
+// number of bytes written so far out of total. It returns w as-is should c not emit events.
+func (c *Client) progressWriter(w io.Writer, total int64) io.Writer {
+	if c.emit == nil {
+		return w
+	}
+	return &progressWriter{Writer: w, client: c, total: total}
+}
+
+// progressWriter implements io.Writer, emitting a Progress event for every write made to an underlying io.Writer.
+type progressWriter struct {
+	io.Writer
+	client *Client
+	done   int64
+	total  int64
+}
+
+// Write implements io.Writer.
+func (w *progressWriter) Write(b []byte) (int, error) {
+	n, err := w.Writer.Write(b)
+	if n > 0 {
+		w.done += int64(n)
+		w.client.event(Progress{Done: w.done, Total: w.total})
+	}
+	return n, err
+}
+
+// eventSink delivers download events into a buffered channel without ever blocking. Should the channel be full, the
+// oldest buffered event is dropped to make room for the newest one.
+type eventSink struct {
+	mu sync.Mutex
+	ch chan DownloadEvent
+}
+
+func newEventSink() *eventSink {
+	return &eventSink{ch: make(chan DownloadEvent, downloadEventBufferSize)}
+}
+
+func (s *eventSink) emit(e DownloadEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		select {
+		case s.ch <- e:
+			return
+		default:
+		}
+
+		select {
+		case <-s.ch:
+		default:
+		}
+	}
+}
+
+func (s *eventSink) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	close(s.ch)
+}
@@ -0,0 +1,202 @@
+package nicehttp
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSerialDownloadReportsProgressPerBuffer(t *testing.T) {
+	contents := testContents(64)
+
+	srv := newContentServer(t, contents, nil)
+
+	dir, err := ioutil.TempDir("", "nicehttp")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := newTestClient(8, WithCopyBufferSize(8))
+	c.AcceptsRanges = false
+
+	events, err := c.DownloadFileEvents(filepath.Join(dir, "file"), srv.URL)
+	if err != nil {
+		t.Fatalf("failed to start download: %s", err)
+	}
+
+	var progress []Progress
+
+	for e := range events {
+		switch e := e.(type) {
+		case Progress:
+			progress = append(progress, e)
+		case Failed:
+			t.Fatalf("failed to download: %s", e.Err)
+		}
+	}
+
+	if len(progress) != len(contents)/8 {
+		t.Fatalf("expected %d progress events, got %d: %+v", len(contents)/8, len(progress), progress)
+	}
+
+	for i, p := range progress {
+		if want := (Progress{Done: int64(i+1) * 8, Total: int64(len(contents))}); p != want {
+			t.Fatalf("expected progress event %d to be %+v, got %+v", i, want, p)
+		}
+	}
+}
+
+func TestEventSinkDropsOldestEvents(t *testing.T) {
+	sink := newEventSink()
+
+	// Emit more events than may be buffered without reading any of them, as would a consumer that is too slow.
+
+	for i := 0; i < downloadEventBufferSize+10; i++ {
+		sink.emit(Progress{Done: int64(i)})
+	}
+	sink.emit(Completed{})
+	sink.close()
+
+	var events []DownloadEvent
+	for e := range sink.ch {
+		events = append(events, e)
+	}
+
+	if len(events) != downloadEventBufferSize {
+		t.Fatalf("expected %d events to be buffered, got %d", downloadEventBufferSize, len(events))
+	}
+
+	for i, e := range events[:len(events)-1] {
+		if want := (Progress{Done: int64(i + 11)}); e != want {
+			t.Fatalf("expected event %d to be %+v, got %+v", i, want, e)
+		}
+	}
+
+	if _, ok := events[len(events)-1].(Completed); !ok {
+		t.Fatalf("expected the last event to be Completed, got %+v", events[len(events)-1])
+	}
+}
+
+func TestChunkedDownloadEvents(t *testing.T) {
+	contents := testContents(64)
+
+	srv := newContentServer(t, contents, nil)
+
+	dir, err := ioutil.TempDir("", "nicehttp")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := newTestClient(8)
+
+	events, err := c.DownloadFileEvents(filepath.Join(dir, "file"), srv.URL+"/file")
+	if err != nil {
+		t.Fatalf("failed to start download: %s", err)
+	}
+
+	var (
+		received []DownloadEvent
+		chunks   = make(map[ChunkDone]bool)
+	)
+
+	for e := range events {
+		received = append(received, e)
+
+		switch e := e.(type) {
+		case ChunkDone:
+			chunks[e] = true
+		case Failed:
+			t.Fatalf("failed to download: %s", e.Err)
+		}
+	}
+
+	if want := (Started{URL: srv.URL + "/file", ContentLength: len(contents), Chunked: true}); received[0] != want {
+		t.Fatalf("expected the first event to be %+v, got %+v", want, received[0])
+	}
+
+	for start := 0; start < len(contents); start += 8 {
+		if !chunks[ChunkDone{Start: start, End: start + 8}] {
+			t.Fatalf("expected ChunkDone to be emitted for byte range [%d, %d), got %v", start, start+8, chunks)
+		}
+	}
+
+	if _, ok := received[len(received)-1].(Completed); !ok {
+		t.Fatalf("expected the last event to be Completed, got %+v", received[len(received)-1])
+	}
+}
+
+func TestChunkedDownloadEventsSlowConsumer(t *testing.T) {
+	contents := testContents(1024)
+
+	srv := newContentServer(t, contents, nil)
+
+	dir, err := ioutil.TempDir("", "nicehttp")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "file")
+
+	// A single worker downloads chunks in order, such that ChunkDone events are emitted in order.
+
+	c := newTestClient(8, WithNumWorkers(1))
+
+	events, err := c.DownloadFileEvents(filename, srv.URL)
+	if err != nil {
+		t.Fatalf("failed to start download: %s", err)
+	}
+
+	// Only start reading events once the file has been downloaded, by which point far more events have been emitted
+	// than may be buffered.
+
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("expected download to complete")
+		}
+		if _, err := os.Stat(filename); err == nil {
+			break
+		}
+	}
+
+	var (
+		received []DownloadEvent
+		ends     []int
+	)
+
+	for e := range events {
+		received = append(received, e)
+
+		switch e := e.(type) {
+		case Started:
+			t.Fatalf("expected Started to be dropped as the oldest event")
+		case ChunkDone:
+			ends = append(ends, e.End)
+		case Failed:
+			t.Fatalf("failed to download: %s", e.Err)
+		}
+	}
+
+	if len(received) < downloadEventBufferSize {
+		t.Fatalf("expected at least %d events to be buffered, got %d", downloadEventBufferSize, len(received))
+	}
+
+	// The events that were kept are the newest ones, i.e. those of the last chunks downloaded.
+
+	if len(ends) == 0 || ends[len(ends)-1] != len(contents) {
+		t.Fatalf("expected ChunkDone of the last chunk to be kept, got chunks ending at %v", ends)
+	}
+	for i := 1; i < len(ends); i++ {
+		if ends[i] != ends[i-1]+8 {
+			t.Fatalf("expected ChunkDone events of consecutive chunks to be kept, got chunks ending at %v", ends)
+		}
+	}
+
+	if _, ok := received[len(received)-1].(Completed); !ok {
+		t.Fatalf("expected the last event to be Completed, got %+v", received[len(received)-1])
+	}
+}
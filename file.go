@@ -0,0 +1,193 @@
+package nicehttp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ErrFileTooLarge is returned by file downloads should the contents of a URL exceed the max file size set using
+// MaxFileSize.
+var ErrFileTooLarge = errors.New("file too large")
+
+// ErrAlreadyDownloaded is returned by file downloads should Client.SkipIfPresent be set, and the file to be
+// downloaded into already match the contents of its URL.
+var ErrAlreadyDownloaded = errors.New("file already downloaded")
+
+// createTempFile creates a new temporary file in dir named after filename. Should dir be empty, the file is created in
+// the same directory as filename, such that it may be atomically renamed to filename.
+func createTempFile(dir, filename string) (*os.File, error) {
+	if dir == "" {
+		dir = filepath.Dir(filename)
+	}
+
+	for i := 0; i < 10000; i++ {
+		name := filepath.Join(dir, filepath.Base(filename)+"."+strconv.FormatUint(uint64(rand.Uint32()), 36)+".tmp")
+
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+
+		return f, err
+	}
+
+	return nil, errors.New("failed to find an unused temp file name")
+}
+
+// moveFile renames src to dst. Should src and dst be on different filesystems, src is instead copied into a temporary
+// file next to dst which is then atomically renamed to dst, and src is removed.
+func (c *Client) moveFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	return c.copyFile(src, dst)
+}
+
+// copyFile copies src into a temporary file next to dst, atomically renames it to dst, and removes src.
+func (c *Client) copyFile(src, dst string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := createTempFile("", dst)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			out.Close()
+			os.Remove(out.Name())
+		}
+	}()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return fmt.Errorf("failed to copy %q across filesystems: %w", src, err)
+	}
+
+	if err := c.sync(out); err != nil {
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(out.Name(), dst); err != nil {
+		return err
+	}
+
+	in.Close()
+
+	return os.Remove(src)
+}
+
+// etagSidecarPath returns the path to the sidecar file storing the ETag of the contents downloaded into filename.
+func etagSidecarPath(filename string) string {
+	return filename + ".etag"
+}
+
+// skipIfPresent returns ErrAlreadyDownloaded should c.SkipIfPresent be set, and filename already match the contents
+// of the URL described by info.
+func (c *Client) skipIfPresent(filename string, info resourceInfo) error {
+	if !c.SkipIfPresent || info.contentLength <= 0 {
+		return nil
+	}
+
+	fi, err := os.Stat(filename)
+	if err != nil || !fi.Mode().IsRegular() || fi.Size() != int64(info.contentLength) {
+		return nil
+	}
+
+	if info.etag != "" {
+		buf, err := ioutil.ReadFile(etagSidecarPath(filename))
+		if err == nil && strings.TrimSpace(string(buf)) != info.etag {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%q matches a content length of %d byte(s): %w", filename, info.contentLength, ErrAlreadyDownloaded)
+}
+
+// recordETag writes the ETag of the URL described by info into the sidecar file of filename should c.SkipIfPresent
+// be set, such that a later download may tell whether or not filename still matches the contents of the URL. A
+// stale sidecar file is removed should the URL not report an ETag.
+func (c *Client) recordETag(filename string, info resourceInfo) error {
+	if !c.SkipIfPresent {
+		return nil
+	}
+
+	path := etagSidecarPath(filename)
+
+	if info.etag == "" {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove etag sidecar file: %w", err)
+		}
+		return nil
+	}
+
+	if err := ioutil.WriteFile(path, []byte(info.etag), 0644); err != nil {
+		return fmt.Errorf("failed to write etag sidecar file: %w", err)
+	}
+
+	return nil
+}
+
+// allocate resizes f to size bytes. Disk space for f is reserved up front should c.PreallocateDisk be set.
+func (c *Client) allocate(f *os.File, size int64) error {
+	if c.PreallocateDisk {
+		return preallocate(f, size)
+	}
+	return f.Truncate(size)
+}
+
+// sync commits the contents of f to disk, unless c.SkipSync is set.
+func (c *Client) sync(f *os.File) error {
+	if c.SkipSync {
+		return nil
+	}
+	return f.Sync()
+}
+
+var _ Writer = (*fileSizeLimiter)(nil)
+
+// fileSizeLimiter implements Writer, failing with ErrFileTooLarge should a write past max bytes into the underlying
+// Writer be attempted. Writes made using WriteAt may be made from multiple goroutines at once.
+type fileSizeLimiter struct {
+	Writer
+	max     int64
+	written int64
+}
+
+// Write implements io.Writer.
+func (w *fileSizeLimiter) Write(b []byte) (int, error) {
+	if w.written+int64(len(b)) > w.max {
+		return 0, fmt.Errorf("wrote more than %d byte(s): %w", w.max, ErrFileTooLarge)
+	}
+
+	n, err := w.Writer.Write(b)
+	w.written += int64(n)
+
+	return n, err
+}
+
+// WriteAt implements io.WriterAt.
+func (w *fileSizeLimiter) WriteAt(b []byte, off int64) (int, error) {
+	if off+int64(len(b)) > w.max {
+		return 0, fmt.Errorf("wrote past %d byte(s): %w", w.max, ErrFileTooLarge)
+	}
+
+	return w.Writer.WriteAt(b, off)
+}
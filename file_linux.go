@@ -0,0 +1,25 @@
+//go:build linux
+// +build linux
+
+package nicehttp
+
+import (
+	"errors"
+	"golang.org/x/sys/unix"
+	"os"
+)
+
+// preallocate reserves size bytes of disk space for f using fallocate(2), such that running out of disk space is
+// reported immediately. It falls back to truncating f should the underlying filesystem not support fallocate(2).
+func preallocate(f *os.File, size int64) error {
+	if size <= 0 {
+		return f.Truncate(size)
+	}
+
+	err := unix.Fallocate(int(f.Fd()), 0, 0, size)
+	if errors.Is(err, unix.EOPNOTSUPP) || errors.Is(err, unix.ENOSYS) {
+		return f.Truncate(size)
+	}
+
+	return err
+}
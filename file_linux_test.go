@@ -0,0 +1,104 @@
+package nicehttp
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/sys/unix"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestPreallocateDisk(t *testing.T) {
+	contents := testContents(64 * 1024)
+
+	dir, err := ioutil.TempDir("", "nicehttp")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	probe, err := os.Create(filepath.Join(dir, "probe"))
+	if err != nil {
+		t.Fatalf("failed to create file: %s", err)
+	}
+	err = unix.Fallocate(int(probe.Fd()), 0, 0, 1)
+	probe.Close()
+
+	if errors.Is(err, unix.EOPNOTSUPP) || errors.Is(err, unix.ENOSYS) {
+		t.Skip("fallocate(2) is not supported by the filesystem of the temp dir")
+	}
+
+	release := make(chan struct{})
+
+	// Hold back every chunk until the file that chunks are to be written into has been inspected.
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" {
+			<-release
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(contents))
+	}))
+	defer srv.Close()
+	defer func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+	}()
+
+	c := newTestClient(8 * 1024)
+	c.PreallocateDisk = true
+
+	filename := filepath.Join(dir, "file")
+
+	errs := make(chan error, 1)
+	go func() { errs <- c.DownloadFile(filename, srv.URL) }()
+
+	var fi os.FileInfo
+
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("expected a temp file to be created before any chunk is written")
+		}
+
+		matches, _ := filepath.Glob(filename + ".*.tmp")
+		if len(matches) != 1 {
+			continue
+		}
+
+		if fi, err = os.Stat(matches[0]); err == nil && fi.Size() > 0 {
+			break
+		}
+	}
+
+	if fi.Size() != int64(len(contents)) {
+		t.Fatalf("expected temp file to be sized to %d byte(s) before any chunk is written, got %d", len(contents), fi.Size())
+	}
+
+	// Disk space reserved for a file is allocated to it, unlike the disk space of a file that is only truncated.
+
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok && st.Blocks*512 < int64(len(contents)) {
+		t.Fatalf("expected %d byte(s) of disk space to be reserved, got %d", len(contents), st.Blocks*512)
+	}
+
+	close(release)
+
+	if err := <-errs; err != nil {
+		t.Fatalf("failed to download: %s", err)
+	}
+
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("failed to read downloaded file: %s", err)
+	}
+	if !bytes.Equal(buf, contents) {
+		t.Fatalf("downloaded contents do not match")
+	}
+}
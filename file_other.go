@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package nicehttp
+
+import "os"
+
+// preallocate truncates f to size bytes, as reserving disk space up front is only supported on Linux.
+func preallocate(f *os.File, size int64) error {
+	return f.Truncate(size)
+}
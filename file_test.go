@@ -0,0 +1,181 @@
+package nicehttp
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDownloadFileTempDir(t *testing.T) {
+	contents := testContents(64)
+
+	srv := newContentServer(t, contents, nil)
+
+	dir, err := ioutil.TempDir("", "nicehttp")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tempDir, dstDir := filepath.Join(dir, "temp"), filepath.Join(dir, "dst")
+
+	for _, d := range []string{tempDir, dstDir} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatalf("failed to create dir: %s", err)
+		}
+	}
+
+	c := newTestClient(8)
+	c.TempDir = tempDir
+
+	filename := filepath.Join(dstDir, "file")
+
+	if err := c.DownloadFile(filename, srv.URL); err != nil {
+		t.Fatalf("failed to download: %s", err)
+	}
+
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("failed to read downloaded file: %s", err)
+	}
+	if !bytes.Equal(buf, contents) {
+		t.Fatalf("downloaded contents do not match")
+	}
+
+	// The temp file staged in the temp dir is renamed to filename once the download completes.
+
+	if files, err := ioutil.ReadDir(tempDir); err != nil || len(files) != 0 {
+		t.Fatalf("expected temp dir to be left empty, got %d file(s) and %v", len(files), err)
+	}
+}
+
+func TestMoveFile(t *testing.T) {
+	contents := testContents(64)
+
+	dir, err := ioutil.TempDir("", "nicehttp")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var c Client
+
+	// check asserts that src was moved to dst, leaving nothing but dst behind in the directory of dst.
+
+	check := func(t *testing.T, src, dst string) {
+		if _, err := os.Stat(src); !os.IsNotExist(err) {
+			t.Fatalf("expected %q to be removed, got %v", src, err)
+		}
+
+		buf, err := ioutil.ReadFile(dst)
+		if err != nil {
+			t.Fatalf("failed to read moved file: %s", err)
+		}
+		if !bytes.Equal(buf, contents) {
+			t.Fatalf("moved contents do not match")
+		}
+
+		files, err := ioutil.ReadDir(filepath.Dir(dst))
+		if err != nil || len(files) != 1 {
+			t.Fatalf("expected only %q to be left behind, got %d file(s) and %v", dst, len(files), err)
+		}
+	}
+
+	t.Run("rename", func(t *testing.T) {
+		src, dst := filepath.Join(dir, "rename.tmp"), filepath.Join(dir, "rename", "file")
+
+		if err := os.Mkdir(filepath.Dir(dst), 0755); err != nil {
+			t.Fatalf("failed to create dir: %s", err)
+		}
+		if err := ioutil.WriteFile(src, contents, 0644); err != nil {
+			t.Fatalf("failed to write file: %s", err)
+		}
+
+		if err := c.moveFile(src, dst); err != nil {
+			t.Fatalf("failed to move file: %s", err)
+		}
+
+		check(t, src, dst)
+	})
+
+	// Renames across filesystems fail with EXDEV, upon which moveFile copies the file instead. The copy is made
+	// directly, as the test may not rely on the temp dir being on a different filesystem.
+
+	t.Run("copy", func(t *testing.T) {
+		src, dst := filepath.Join(dir, "copy.tmp"), filepath.Join(dir, "copy", "file")
+
+		if err := os.Mkdir(filepath.Dir(dst), 0755); err != nil {
+			t.Fatalf("failed to create dir: %s", err)
+		}
+		if err := ioutil.WriteFile(src, contents, 0644); err != nil {
+			t.Fatalf("failed to write file: %s", err)
+		}
+
+		if err := c.copyFile(src, dst); err != nil {
+			t.Fatalf("failed to copy file: %s", err)
+		}
+
+		check(t, src, dst)
+	})
+}
+
+func TestSerialDownloadDoesNotSizeFile(t *testing.T) {
+	contents := testContents(64)
+
+	srv := newContentServer(t, contents, nil)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	dir, err := ioutil.TempDir("", "nicehttp")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := newTestClient(8)
+	c.CheckStatus = true
+	c.KeepPartialFiles = true
+
+	// Contents whose length is not known are downloaded serially straight into the file.
+
+	filename := filepath.Join(dir, "unsized")
+
+	if err := c.DownloadFileSized(filename, srv.URL, 0, false); err != nil {
+		t.Fatalf("failed to download: %s", err)
+	}
+
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("failed to read downloaded file: %s", err)
+	}
+	if !bytes.Equal(buf, contents) {
+		t.Fatalf("downloaded contents do not match")
+	}
+
+	// A serial download that fails before writing anything leaves behind an empty partial file, rather than one
+	// sized to the content length up front.
+
+	if err := c.DownloadFileSized(filepath.Join(dir, "failed"), failing.URL, len(contents), false); err == nil {
+		t.Fatalf("expected download to fail")
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, "failed.*.tmp"))
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("expected a partial file to be kept, got %v and %v", matches, err)
+	}
+
+	fi, err := os.Stat(matches[0])
+	if err != nil {
+		t.Fatalf("failed to stat partial file: %s", err)
+	}
+	if fi.Size() != 0 {
+		t.Fatalf("expected partial file of a serial download to not be sized up front, got %d byte(s)", fi.Size())
+	}
+}
@@ -0,0 +1,150 @@
+package nicehttp
+
+import (
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"mime"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxFilenameLength is the max length in bytes of a filename suggested by a server.
+const maxFilenameLength = 255
+
+// DownloadFileTo downloads the contents of url, and writes its contents to a newly-created file in directory dir. The
+// file is named after the filename suggested by the Content-Disposition header of url, or otherwise after the last
+// path segment of url. It returns the path to the file.
+//
+// As the filename is suggested by the server, it is sanitized such that it may not contain any path separators, may
+// not refer to a parent directory, may not refer to a hidden file, and may not refer to a device reserved on Windows.
+func (c *Client) DownloadFileTo(dir, url string, opts ...DownloadOption) (string, error) {
+	return c.DownloadFileToDeadline(dir, url, zeroTime, opts...)
+}
+
+// DownloadFileToTimeout downloads the contents of url, and writes its contents to a newly-created file in directory
+// dir named after the filename suggested by url. It returns the path to the file.
+func (c *Client) DownloadFileToTimeout(dir, url string, timeout time.Duration, opts ...DownloadOption) (string, error) {
+	return c.DownloadFileToDeadline(dir, url, c.clock().Add(timeout), opts...)
+}
+
+// DownloadFileToDeadline downloads the contents of url, and writes its contents to a newly-created file in directory
+// dir named after the filename suggested by url. It returns the path to the file.
+func (c *Client) DownloadFileToDeadline(dir, url string, deadline time.Time, opts ...DownloadOption) (string, error) {
+	o := newDownloadOptions(opts)
+
+	info, err := c.queryInfo(url, deadline, nil)
+	if queryFailed(err) {
+		return "", err
+	}
+
+	c = c.forTarget(url, info)
+
+	o.resolve(url, info)
+
+	name := filenameFromContentDisposition(info.contentDisposition)
+	if name == "" {
+		name = filenameFromURL(url)
+	}
+
+	if name == "" {
+		return "", fmt.Errorf("unable to determine a filename to download %q to", url)
+	}
+
+	filename := filepath.Join(dir, name)
+
+	if err := c.skipIfPresent(filename, info); err != nil {
+		return filename, err
+	}
+
+	if err := c.downloadFile(filename, info.target(url), info.contentLength, info.acceptsRanges, deadline, o); err != nil {
+		return "", err
+	}
+
+	if err := c.recordETag(filename, info); err != nil {
+		return filename, err
+	}
+
+	return filename, nil
+}
+
+// filenameFromContentDisposition returns the sanitized filename suggested by a Content-Disposition header. It returns
+// an empty string should the header not suggest a filename.
+func filenameFromContentDisposition(header string) string {
+	if header == "" {
+		return ""
+	}
+
+	_, params, err := mime.ParseMediaType(header)
+	if err != nil {
+		return ""
+	}
+
+	return sanitizeFilename(params["filename"])
+}
+
+// filenameFromURL returns the sanitized last path segment of url.
+func filenameFromURL(url string) string {
+	uri := fasthttp.AcquireURI()
+	defer fasthttp.ReleaseURI(uri)
+
+	uri.Update(url)
+
+	return sanitizeFilename(string(uri.LastPathSegment()))
+}
+
+// sanitizeFilename strips away from name any directories, control characters, characters that are reserved on
+// Windows, and leading dots. Names of devices that are reserved on Windows, such as CON or COM1.txt, are prefixed with
+// an underscore. It returns an empty string should nothing of name remain.
+func sanitizeFilename(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = path.Base(name)
+
+	if name == "/" {
+		return ""
+	}
+
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsControl(r), r == unicode.ReplacementChar:
+			return -1
+		case strings.ContainsRune(`<>:"/|?*`, r):
+			return '_'
+		}
+		return r
+	}, name)
+
+	name = strings.Trim(name, ". ")
+
+	if isReservedFilename(name) {
+		name = "_" + name
+	}
+
+	for len(name) > maxFilenameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+
+	return strings.TrimRight(name, ". ")
+}
+
+// isReservedFilename returns whether or not name refers to a device on Windows, regardless of its extension.
+func isReservedFilename(name string) bool {
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		name = name[:i]
+	}
+
+	switch name = strings.ToUpper(strings.TrimRight(name, " ")); name {
+	case "CON", "PRN", "AUX", "NUL", "CONIN$", "CONOUT$":
+		return true
+	}
+
+	if len(name) == 4 && (strings.HasPrefix(name, "COM") || strings.HasPrefix(name, "LPT")) {
+		return name[3] >= '0' && name[3] <= '9'
+	}
+
+	return false
+}
@@ -0,0 +1,153 @@
+package nicehttp
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	long := strings.Repeat("é", 200)
+
+	tests := []struct {
+		name, expected string
+	}{
+		{name: "file.txt", expected: "file.txt"},
+		{name: "../../etc/passwd", expected: "passwd"},
+		{name: `..\..\x`, expected: "x"},
+		{name: "/etc/passwd", expected: "passwd"},
+		{name: `C:\Windows\system.ini`, expected: "system.ini"},
+		{name: "..", expected: ""},
+		{name: "../", expected: ""},
+		{name: "/", expected: ""},
+		{name: ".hidden", expected: "hidden"},
+		{name: "trailing. . ", expected: "trailing"},
+		{name: "nul\x00byte\x1f\x7f.txt", expected: "nulbyte.txt"},
+		{name: "a<b>c:d\"e|f?g*h", expected: "a_b_c_d_e_f_g_h"},
+		{name: "CON", expected: "_CON"},
+		{name: "nul.txt", expected: "_nul.txt"},
+		{name: "com1.tar.gz", expected: "_com1.tar.gz"},
+		{name: "LPT9", expected: "_LPT9"},
+		{name: "console.txt", expected: "console.txt"},
+		{name: "COM10", expected: "COM10"},
+		{name: long, expected: strings.Repeat("é", maxFilenameLength/2)},
+	}
+
+	for _, test := range tests {
+		if got := sanitizeFilename(test.name); got != test.expected {
+			t.Fatalf("sanitizeFilename(%q) = %q, expected %q", test.name, got, test.expected)
+		}
+	}
+
+	if got := sanitizeFilename(long); len(got) > maxFilenameLength || !utf8.ValidString(got) {
+		t.Fatalf("expected long names to be truncated to valid UTF-8 of at most %d bytes, got %d bytes", maxFilenameLength, len(got))
+	}
+}
+
+func TestFilenameFromContentDisposition(t *testing.T) {
+	tests := []struct {
+		header, expected string
+	}{
+		{header: "", expected: ""},
+		{header: "inline", expected: ""},
+		{header: `attachment; filename="report.pdf"`, expected: "report.pdf"},
+		{header: `attachment; filename="../../etc/passwd"`, expected: "passwd"},
+		{header: `attachment; filename="..\\..\\x"`, expected: "x"},
+		{header: `attachment; filename*=UTF-8''%e2%82%ac%20rates.txt`, expected: "€ rates.txt"},
+		{header: `attachment; filename*=UTF-8''..%2F..%2Fetc%2Fpasswd`, expected: "passwd"},
+		{header: `attachment; filename*=UTF-8''evil%00.txt`, expected: "evil.txt"},
+		{header: `attachment; filename="CON.txt"`, expected: "_CON.txt"},
+		{header: `attachment; filename="unterminated`, expected: ""},
+	}
+
+	for _, test := range tests {
+		if got := filenameFromContentDisposition(test.header); got != test.expected {
+			t.Fatalf("filenameFromContentDisposition(%q) = %q, expected %q", test.header, got, test.expected)
+		}
+	}
+}
+
+func TestFilenameFromURL(t *testing.T) {
+	tests := []struct {
+		url, expected string
+	}{
+		{url: "http://example.com/files/report.pdf", expected: "report.pdf"},
+		{url: "http://example.com/files/report.pdf?version=2", expected: "report.pdf"},
+		{url: "http://example.com/files/%2e%2e%2f%2e%2e%2fpasswd", expected: "passwd"},
+		{url: "http://example.com/files/..%5c..%5cx", expected: "x"},
+		{url: "http://example.com/files/%00evil", expected: "evil"},
+		{url: "http://example.com/", expected: ""},
+	}
+
+	for _, test := range tests {
+		if got := filenameFromURL(test.url); got != test.expected {
+			t.Fatalf("filenameFromURL(%q) = %q, expected %q", test.url, got, test.expected)
+		}
+	}
+}
+
+func TestDownloadFileTo(t *testing.T) {
+	contents := testContents(64)
+
+	var disposition string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if disposition != "" {
+			w.Header().Set("Content-Disposition", disposition)
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(contents))
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "nicehttp")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := newTestClient(8)
+
+	tests := []struct {
+		disposition, path, expected string
+	}{
+		{path: "/files/report.pdf", expected: "report.pdf"},
+		{disposition: `attachment; filename="../../escaped"`, path: "/file", expected: "escaped"},
+		{disposition: `attachment; filename="NUL"`, path: "/file", expected: "_NUL"},
+		{path: "/files/%2e%2e%2f%2e%2e%2fescaped-url", expected: "escaped-url"},
+	}
+
+	for _, test := range tests {
+		disposition = test.disposition
+
+		filename, err := c.DownloadFileTo(dir, srv.URL+test.path)
+		if err != nil {
+			t.Fatalf("failed to download: %s", err)
+		}
+		if expected := filepath.Join(dir, test.expected); filename != expected {
+			t.Fatalf("expected contents to be downloaded to %q, got %q", expected, filename)
+		}
+
+		buf, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatalf("failed to read downloaded file: %s", err)
+		}
+		if !bytes.Equal(buf, contents) {
+			t.Fatalf("downloaded contents do not match")
+		}
+	}
+
+	// Nothing may have been written outside of dir.
+
+	for _, name := range []string{"escaped", "escaped-url"} {
+		if _, err := os.Stat(filepath.Join(filepath.Dir(dir), name)); !os.IsNotExist(err) {
+			t.Fatalf("expected nothing to be written outside of %q, got %v", dir, err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package nicehttp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// isFileRequest reports whether or not req is for a file:// URL, or for a plain local path. A request for a plain
+// local path is one for a URL that has neither a scheme nor a host, which may never be sent over HTTP.
+func isFileRequest(req *fasthttp.Request) bool {
+	uri := req.URI()
+	return string(uri.Scheme()) == "file" || len(uri.Host()) == 0
+}
+
+// serveFile populates res as though req were served by a HTTP server serving the local filesystem. HEAD requests are
+// responded to with the size of the file requested as their content length, and requests for a single byte range are
+// responded to with said byte range, such that files may be downloaded in chunks.
+func serveFile(req *fasthttp.Request, res *fasthttp.Response) error {
+	res.Reset()
+
+	path := filepath.FromSlash(string(req.URI().Path()))
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			res.SetStatusCode(fasthttp.StatusNotFound)
+			return nil
+		}
+		return fmt.Errorf("failed to open %q: %w", path, err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %q: %w", path, err)
+	}
+
+	if fi.IsDir() {
+		res.SetStatusCode(fasthttp.StatusNotFound)
+		return nil
+	}
+
+	size := fi.Size()
+
+	res.Header.Set("Accept-Ranges", "bytes")
+	res.Header.SetLastModified(fi.ModTime())
+
+	start, end := int64(0), size
+
+	if value := req.Header.Peek("Range"); len(value) > 0 {
+		first, last, ok := parseRangeHeader(value, size)
+		if ok && first >= size {
+			res.SetStatusCode(fasthttp.StatusRequestedRangeNotSatisfiable)
+			res.Header.Set("Content-Range", "bytes */"+strconv.FormatInt(size, 10))
+			return nil
+		}
+
+		if ok {
+			start, end = first, last+1
+
+			res.SetStatusCode(fasthttp.StatusPartialContent)
+			res.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", first, last, size))
+		}
+	}
+
+	if !req.Header.IsHead() {
+		if _, err := io.CopyN(res.BodyWriter(), io.NewSectionReader(f, start, end-start), end-start); err != nil {
+			return fmt.Errorf("failed to read %q: %w", path, err)
+		}
+	}
+
+	res.Header.SetContentLength(int(end - start))
+
+	return nil
+}
+
+// parseRangeHeader parses the value of a Range header requesting a single byte range of contents comprised of size
+// bytes into the first and last byte of said range. ok is false should value not request a single byte range, in
+// which case the entirety of the contents are to be served instead. first is not less than size should the range
+// not be satisfiable.
+func parseRangeHeader(value []byte, size int64) (first, last int64, ok bool) {
+	value = bytes.TrimSpace(value)
+
+	if !bytes.HasPrefix(value, []byte("bytes=")) || bytes.IndexByte(value, ',') >= 0 {
+		return 0, 0, false
+	}
+	value = bytes.TrimSpace(value[len("bytes="):])
+
+	dash := bytes.IndexByte(value, '-')
+	if dash < 0 {
+		return 0, 0, false
+	}
+
+	lhs, rhs := bytes.TrimSpace(value[:dash]), bytes.TrimSpace(value[dash+1:])
+
+	// A range of the form "-n" requests the last n bytes.
+
+	if len(lhs) == 0 {
+		n, err := strconv.ParseInt(string(rhs), 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, false
+		}
+		if n > size {
+			n = size
+		}
+		if n == 0 {
+			return size, size, true
+		}
+		return size - n, size - 1, true
+	}
+
+	first, err := strconv.ParseInt(string(lhs), 10, 64)
+	if err != nil || first < 0 {
+		return 0, 0, false
+	}
+
+	last = size - 1
+
+	if len(rhs) > 0 {
+		if last, err = strconv.ParseInt(string(rhs), 10, 64); err != nil || last < first {
+			return 0, 0, false
+		}
+		if last > size-1 {
+			last = size - 1
+		}
+	}
+
+	return first, last, true
+}
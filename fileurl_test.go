@@ -0,0 +1,85 @@
+package nicehttp
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileURLs(t *testing.T) {
+	contents := testContents(64)
+
+	dir, err := ioutil.TempDir("", "nicehttp")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(path, contents, 0644); err != nil {
+		t.Fatalf("failed to write file: %s", err)
+	}
+
+	c := newTestClient(8)
+	c.AllowFileURLs = true
+
+	for _, url := range []string{"file://" + filepath.ToSlash(path), filepath.ToSlash(path)} {
+		info, err := c.QueryInfo(url)
+		if err != nil {
+			t.Fatalf("failed to query headers of %q: %s", url, err)
+		}
+		if info.ContentLength != len(contents) || !info.AcceptsRanges {
+			t.Fatalf("expected %q to report its size and accept ranges, got %+v", url, info)
+		}
+
+		// Download the file in chunks, such that every chunk is served from a byte range of the file.
+
+		buf := NewWriteBuffer(make([]byte, len(contents)))
+		if err := c.DownloadInChunks(buf, url, len(contents)); err != nil {
+			t.Fatalf("failed to download %q in chunks: %s", url, err)
+		}
+		if !bytes.Equal(buf.Bytes(), contents) {
+			t.Fatalf("contents of %q downloaded in chunks do not match", url)
+		}
+
+		dst, err := c.DownloadBytes(nil, url)
+		if err != nil {
+			t.Fatalf("failed to download %q: %s", url, err)
+		}
+		if !bytes.Equal(dst, contents) {
+			t.Fatalf("contents of %q do not match", url)
+		}
+	}
+
+	c.AllowFileURLs = false
+
+	if _, err := c.DownloadBytes(nil, "file://"+filepath.ToSlash(path)); err == nil {
+		t.Fatalf("expected file URLs to be refused unless AllowFileURLs is set")
+	}
+}
+
+func TestParseRangeHeader(t *testing.T) {
+	tests := []struct {
+		value       string
+		first, last int64
+		ok          bool
+	}{
+		{value: "bytes=0-7", first: 0, last: 7, ok: true},
+		{value: "bytes=8-", first: 8, last: 63, ok: true},
+		{value: "bytes=60-100", first: 60, last: 63, ok: true},
+		{value: "bytes=-4", first: 60, last: 63, ok: true},
+		{value: "bytes=64-70", first: 64, last: 63, ok: true},
+		{value: "bytes=7-0", ok: false},
+		{value: "bytes=0-1,4-5", ok: false},
+		{value: "items=0-7", ok: false},
+	}
+
+	for _, test := range tests {
+		first, last, ok := parseRangeHeader([]byte(test.value), 64)
+		if ok != test.ok || (ok && (first != test.first || last != test.last)) {
+			t.Fatalf("parseRangeHeader(%q) = %d, %d, %t, expected %d, %d, %t", test.value, first, last, ok, test.first, test.last, test.ok)
+		}
+	}
+}
@@ -6,4 +6,5 @@ require (
 	github.com/lithdew/bytesutil v0.0.0-20200409052507-d98389230a59
 	github.com/valyala/fasthttp v1.12.0
 	golang.org/x/sync v0.0.0-20200317015054-43a5402ce75a
+	golang.org/x/sys v0.0.0-20200323222414-85ca7c5b95cd
 )
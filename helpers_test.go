@@ -0,0 +1,132 @@
+package nicehttp
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testContents returns n bytes of contents that differ at every offset modulo 251, such that misplaced chunks are
+// caught.
+func testContents(n int) []byte {
+	buf := make([]byte, n)
+	for i := range buf {
+		buf[i] = byte(i % 251)
+	}
+	return buf
+}
+
+// recordedRequest is a request received by a test server.
+type recordedRequest struct {
+	Method        string
+	Range         string
+	Authorization string
+	Header        http.Header
+}
+
+// requestLog records the requests received by a test server.
+type requestLog struct {
+	mu       sync.Mutex
+	requests []recordedRequest
+}
+
+func (l *requestLog) record(r *http.Request) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.requests = append(l.requests, recordedRequest{
+		Method:        r.Method,
+		Range:         r.Header.Get("Range"),
+		Authorization: r.Header.Get("Authorization"),
+		Header:        r.Header.Clone(),
+	})
+}
+
+func (l *requestLog) all() []recordedRequest {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]recordedRequest(nil), l.requests...)
+}
+
+// newContentServer starts a test server which serves contents at every path, supporting HEAD requests and byte
+// ranges, and which records every request it receives into log should log not be nil.
+func newContentServer(t *testing.T, contents []byte, log *requestLog) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if log != nil {
+			log.record(r)
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(contents))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// newRedirectServer starts a test server which redirects every request to target with a 302, and which records every
+// request it receives into log should log not be nil.
+func newRedirectServer(t *testing.T, target string, log *requestLog) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if log != nil {
+			log.record(r)
+		}
+		http.Redirect(w, r, target, http.StatusFound)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+// newTestClient instantiates a client which downloads in chunks of chunkSize bytes using two workers, configured
+// further by opts.
+func newTestClient(chunkSize int, opts ...Option) Client {
+	return NewClientWith(append([]Option{WithNumWorkers(2), WithChunkSize(chunkSize)}, opts...)...)
+}
+
+var _ Transport = transportFunc(nil)
+
+// transportFunc is a stub Transport which responds to every request using a func.
+type transportFunc func(req *fasthttp.Request, res *fasthttp.Response) error
+
+func (f transportFunc) Do(req *fasthttp.Request, res *fasthttp.Response) error {
+	return f(req, res)
+}
+
+func (f transportFunc) DoTimeout(req *fasthttp.Request, res *fasthttp.Response, _ time.Duration) error {
+	return f(req, res)
+}
+
+func (f transportFunc) DoDeadline(req *fasthttp.Request, res *fasthttp.Response, _ time.Time) error {
+	return f(req, res)
+}
+
+// rangeTransport returns a stub Transport which serves byte ranges of contents, and the entirety of contents to
+// requests for no byte range.
+func rangeTransport(contents []byte) transportFunc {
+	return func(req *fasthttp.Request, res *fasthttp.Response) error {
+		var start, end int
+		if _, err := fmt.Sscanf(string(req.Header.Peek("Range")), "bytes=%d-%d", &start, &end); err != nil {
+			res.SetStatusCode(fasthttp.StatusOK)
+			res.SetBody(contents)
+			return nil
+		}
+
+		if end >= len(contents) {
+			end = len(contents) - 1
+		}
+
+		res.SetStatusCode(fasthttp.StatusPartialContent)
+		res.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(contents)))
+		res.SetBody(contents[start : end+1])
+		return nil
+	}
+}
@@ -0,0 +1,56 @@
+package nicehttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"time"
+)
+
+// DownloadJSON downloads the contents of url, and unmarshals them as JSON into v. It fails with a *HTTPError should
+// the response not have a 2xx status code. Requests carry an "Accept: application/json" header unless c.Header sets
+// an Accept header. Responses encoded using gzip or deflate are decompressed unless c.DisableDecompression is set.
+func (c *Client) DownloadJSON(url string, v interface{}) error {
+	return c.DownloadJSONDeadline(url, v, zeroTime)
+}
+
+// DownloadJSONTimeout downloads the contents of url, and unmarshals them as JSON into v.
+func (c *Client) DownloadJSONTimeout(url string, v interface{}, timeout time.Duration) error {
+	return c.DownloadJSONDeadline(url, v, c.clock().Add(timeout))
+}
+
+// DownloadJSONDeadline downloads the contents of url, and unmarshals them as JSON into v.
+func (c *Client) DownloadJSONDeadline(url string, v interface{}, deadline time.Time) error {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	res := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(res)
+
+	req.SetRequestURI(url)
+
+	if _, exists := c.Header["Accept"]; !exists {
+		req.Header.Set("Accept", "application/json")
+	}
+
+	if err := c.DoDeadline(req, res, deadline); err != nil {
+		return fmt.Errorf("failed to download %q: %w", url, err)
+	}
+
+	if status := res.StatusCode(); status < fasthttp.StatusOK || status >= fasthttp.StatusMultipleChoices {
+		return fmt.Errorf("failed to download %q: %w", url, newHTTPError(res))
+	}
+
+	var buf bytes.Buffer
+
+	if _, err := c.writeBody(&buf, res); err != nil {
+		return fmt.Errorf("failed to download %q: %w", url, err)
+	}
+
+	if err := json.Unmarshal(buf.Bytes(), v); err != nil {
+		return fmt.Errorf("failed to decode json from %q: %w", url, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+// Package lru provides a size-bounded, least-recently-used nicehttp.Cache.
+package lru
+
+import (
+	"container/list"
+	"github.com/lithdew/nicehttp"
+	"sync"
+)
+
+var _ nicehttp.Cache = (*Cache)(nil)
+
+// Cache is a nicehttp.Cache which holds up to a max number of bytes of contents. Should storing an entry have the
+// cache hold more than said number of bytes, the least-recently used entries are evicted until it no longer does.
+// It is safe to use from multiple goroutines at once.
+type Cache struct {
+	mu sync.Mutex
+
+	maxBytes int64
+	size     int64
+
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+// item is an entry of a Cache alongside the URL it is keyed by.
+type item struct {
+	url   string
+	entry nicehttp.CacheEntry
+}
+
+// New instantiates a Cache which holds up to maxBytes bytes of contents.
+func New(maxBytes int64) *Cache {
+	return &Cache{maxBytes: maxBytes, entries: make(map[string]*list.Element), order: list.New()}
+}
+
+// Get returns the entry stored for url, and marks it as the most-recently used entry.
+func (c *Cache) Get(url string) (nicehttp.CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, exists := c.entries[url]
+	if !exists {
+		return nicehttp.CacheEntry{}, false
+	}
+
+	c.order.MoveToFront(elem)
+
+	return elem.Value.(*item).entry, true
+}
+
+// Set stores entry for url, replacing any entry previously stored for url, and evicts the least-recently used
+// entries should the cache hold more than its max number of bytes. Entries larger than the max number of bytes are
+// not stored.
+func (c *Cache) Set(url string, entry nicehttp.CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, exists := c.entries[url]; exists {
+		c.remove(elem)
+	}
+
+	if int64(len(entry.Body)) > c.maxBytes {
+		return
+	}
+
+	c.entries[url] = c.order.PushFront(&item{url: url, entry: entry})
+	c.size += int64(len(entry.Body))
+
+	for c.size > c.maxBytes {
+		c.remove(c.order.Back())
+	}
+}
+
+// Len returns the number of entries stored.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// Size returns the number of bytes of contents stored.
+func (c *Cache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.size
+}
+
+// remove evicts elem from c. It must be called with c.mu held.
+func (c *Cache) remove(elem *list.Element) {
+	it := c.order.Remove(elem).(*item)
+
+	delete(c.entries, it.url)
+	c.size -= int64(len(it.entry.Body))
+}
@@ -0,0 +1,63 @@
+package nicehttp
+
+import (
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"time"
+)
+
+// PostBytes sends a POST request to url with body of type contentType, and returns the body of the response. Redirects
+// are followed as they are by Do, where a 303 turns the request into a GET request with no body.
+func (c *Client) PostBytes(url, contentType string, body []byte) ([]byte, error) {
+	return c.PostBytesDeadline(url, contentType, body, zeroTime)
+}
+
+// PostBytesTimeout sends a POST request to url with body of type contentType, and returns the body of the response.
+func (c *Client) PostBytesTimeout(url, contentType string, body []byte, timeout time.Duration) ([]byte, error) {
+	return c.PostBytesDeadline(url, contentType, body, c.clock().Add(timeout))
+}
+
+// PostBytesDeadline sends a POST request to url with body of type contentType, and returns the body of the response.
+func (c *Client) PostBytesDeadline(url, contentType string, body []byte, deadline time.Time) ([]byte, error) {
+	return c.sendBytes(fasthttp.MethodPost, url, contentType, body, deadline)
+}
+
+// PutBytes sends a PUT request to url with body of type contentType, and returns the body of the response.
+func (c *Client) PutBytes(url, contentType string, body []byte) ([]byte, error) {
+	return c.PutBytesDeadline(url, contentType, body, zeroTime)
+}
+
+// PutBytesTimeout sends a PUT request to url with body of type contentType, and returns the body of the response.
+func (c *Client) PutBytesTimeout(url, contentType string, body []byte, timeout time.Duration) ([]byte, error) {
+	return c.PutBytesDeadline(url, contentType, body, c.clock().Add(timeout))
+}
+
+// PutBytesDeadline sends a PUT request to url with body of type contentType, and returns the body of the response.
+func (c *Client) PutBytesDeadline(url, contentType string, body []byte, deadline time.Time) ([]byte, error) {
+	return c.sendBytes(fasthttp.MethodPut, url, contentType, body, deadline)
+}
+
+// sendBytes sends a request with the given method to url with body of type contentType, and returns a copy of the body
+// of the response.
+func (c *Client) sendBytes(method, url, contentType string, body []byte, deadline time.Time) ([]byte, error) {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	res := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(res)
+
+	req.Header.SetMethod(method)
+	req.SetRequestURI(url)
+	req.Header.SetContentType(contentType)
+	req.SetBody(body)
+
+	if err := c.DoDeadline(req, res, deadline); err != nil {
+		return nil, fmt.Errorf("failed to send %s request to %q: %w", method, url, err)
+	}
+
+	if err := c.checkStatus(res); err != nil {
+		return nil, fmt.Errorf("failed to send %s request to %q: %w", method, url, err)
+	}
+
+	return append([]byte(nil), res.Body()...), nil
+}
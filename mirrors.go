@@ -0,0 +1,49 @@
+package nicehttp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// DownloadFromMirrors downloads the contents comprised of length bytes served by each of urls in chunks using
+// multiple workers, and stores it in writer f. All of urls are expected to serve the exact same contents, which is
+// verified by checking that each of urls reports a content length of length bytes before the download starts.
+//
+// Workers are spread out across urls. Should downloading a chunk from one of urls fail, the chunk is downloaded from
+// the next one of urls instead.
+func (c *Client) DownloadFromMirrors(f io.WriterAt, urls []string, length int) error {
+	return c.DownloadFromMirrorsDeadline(f, urls, length, zeroTime)
+}
+
+// DownloadFromMirrorsTimeout downloads the contents comprised of length bytes served by each of urls in chunks using
+// multiple workers, and stores it in writer f.
+func (c *Client) DownloadFromMirrorsTimeout(f io.WriterAt, urls []string, length int, timeout time.Duration) error {
+	return c.DownloadFromMirrorsDeadline(f, urls, length, c.clock().Add(timeout))
+}
+
+// DownloadFromMirrorsDeadline downloads the contents comprised of length bytes served by each of urls in chunks using
+// multiple workers, and stores it in writer f.
+func (c *Client) DownloadFromMirrorsDeadline(f io.WriterAt, urls []string, length int, deadline time.Time) error {
+	if len(urls) == 0 {
+		return errors.New("no mirrors to download from")
+	}
+
+	for _, url := range urls {
+		info, err := c.queryInfo(url, deadline, nil)
+		if err != nil {
+			return fmt.Errorf("failed to query headers of mirror %q: %w", url, err)
+		}
+
+		if info.contentLength != length {
+			return fmt.Errorf("mirror %q reported a content length of %d byte(s), but expected %d byte(s)", url, info.contentLength, length)
+		}
+
+		if !info.acceptsRanges {
+			return fmt.Errorf("mirror %q does not accept parallel chunk fetching", url)
+		}
+	}
+
+	return c.downloadInChunks(f, urls, length, deadline, nil)
+}
@@ -0,0 +1,102 @@
+package nicehttp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var (
+	_ Writer    = (*MmapWriter)(nil)
+	_ io.Closer = (*MmapWriter)(nil)
+)
+
+// ErrWriteOutOfRange is returned by MmapWriter should a write extend past the end of the mapped file.
+var ErrWriteOutOfRange = errors.New("write out of range of mapped file")
+
+// MmapWriter is a Writer which writes into a pre-sized file that is memory-mapped, such that chunks downloaded in
+// parallel are written straight into mapped memory rather than through a syscall per write. It is only supported on
+// Linux, macOS and FreeBSD.
+//
+// WriteAt may be called concurrently for disjoint byte ranges. Write appends at an offset which starts at zero, and
+// must not be called concurrently. Close must be called once all writes are done to flush the mapped memory to disk.
+type MmapWriter struct {
+	f    *os.File
+	data []byte
+	off  int64
+}
+
+// NewMmapWriter creates or truncates the file titled filename, resizes it to size bytes, and memory-maps it.
+func NewMmapWriter(filename string, size int) (*MmapWriter, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("invalid size %d", size)
+	}
+
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	if err := f.Truncate(int64(size)); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to resize file to %d byte(s): %w", size, err)
+	}
+
+	var data []byte
+
+	if size > 0 {
+		if data, err = mmap(f, size); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to mmap file: %w", err)
+		}
+	}
+
+	return &MmapWriter{f: f, data: data}, nil
+}
+
+// WriteAt implements io.WriterAt. Writes that extend past the end of the mapped file are written up until its end,
+// and fail with ErrWriteOutOfRange.
+func (w *MmapWriter) WriteAt(b []byte, off int64) (int, error) {
+	if off < 0 || off > int64(len(w.data)) {
+		return 0, ErrWriteOutOfRange
+	}
+
+	n := copy(w.data[off:], b)
+	if n < len(b) {
+		return n, ErrWriteOutOfRange
+	}
+
+	return n, nil
+}
+
+// Write implements io.Writer.
+func (w *MmapWriter) Write(b []byte) (int, error) {
+	n, err := w.WriteAt(b, w.off)
+	w.off += int64(n)
+	return n, err
+}
+
+// Close flushes the mapped memory to disk, unmaps it, and closes the underlying file. The mapped memory is unmapped
+// and the file is closed even should flushing fail, with the first error encountered being returned.
+func (w *MmapWriter) Close() error {
+	var err error
+
+	if w.data != nil {
+		if serr := msync(w.data); serr != nil {
+			err = fmt.Errorf("failed to msync file: %w", serr)
+		}
+
+		if uerr := munmap(w.data); uerr != nil && err == nil {
+			err = fmt.Errorf("failed to munmap file: %w", uerr)
+		}
+
+		w.data = nil
+	}
+
+	if cerr := w.f.Close(); cerr != nil && err == nil {
+		err = fmt.Errorf("failed to close file: %w", cerr)
+	}
+
+	return err
+}
@@ -0,0 +1,24 @@
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package nicehttp
+
+import (
+	"errors"
+	"os"
+)
+
+// errMmapUnsupported is returned by NewMmapWriter on platforms that memory-mapping files is not supported on.
+var errMmapUnsupported = errors.New("mmap is not supported on this platform")
+
+func mmap(f *os.File, size int) ([]byte, error) {
+	return nil, errMmapUnsupported
+}
+
+func msync(b []byte) error {
+	return errMmapUnsupported
+}
+
+func munmap(b []byte) error {
+	return errMmapUnsupported
+}
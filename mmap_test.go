@@ -0,0 +1,75 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package nicehttp
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMmapWriter(t *testing.T) {
+	// The size of the contents is not a multiple of the chunk size, such that the last chunk is shorter than the rest.
+
+	contents := testContents(100)
+
+	srv := newContentServer(t, contents, nil)
+
+	dir, err := ioutil.TempDir("", "nicehttp")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "file")
+
+	w, err := NewMmapWriter(filename, len(contents))
+	if err != nil {
+		t.Fatalf("failed to mmap file: %s", err)
+	}
+
+	c := newTestClient(8, WithNumWorkers(4))
+
+	if err := c.DownloadInChunks(w, srv.URL, len(contents)); err != nil {
+		w.Close()
+		t.Fatalf("failed to download: %s", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close mapped file: %s", err)
+	}
+
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("failed to read downloaded file: %s", err)
+	}
+	if !bytes.Equal(buf, contents) {
+		t.Fatalf("downloaded contents do not match")
+	}
+}
+
+func TestMmapWriterOutOfRange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nicehttp")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	w, err := NewMmapWriter(filepath.Join(dir, "file"), 8)
+	if err != nil {
+		t.Fatalf("failed to mmap file: %s", err)
+	}
+	defer w.Close()
+
+	if n, err := w.WriteAt([]byte("0123456789"), 4); n != 4 || !errors.Is(err, ErrWriteOutOfRange) {
+		t.Fatalf("expected 4 bytes to be written up until the end of the file and ErrWriteOutOfRange, got %d and %v", n, err)
+	}
+
+	if n, err := w.WriteAt([]byte("0"), 9); n != 0 || !errors.Is(err, ErrWriteOutOfRange) {
+		t.Fatalf("expected no bytes to be written past the end of the file and ErrWriteOutOfRange, got %d and %v", n, err)
+	}
+}
@@ -0,0 +1,24 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package nicehttp
+
+import (
+	"golang.org/x/sys/unix"
+	"os"
+)
+
+// mmap maps the first size bytes of f into memory for reading and writing, with writes being shared with f.
+func mmap(f *os.File, size int) ([]byte, error) {
+	return unix.Mmap(int(f.Fd()), 0, size, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED)
+}
+
+// msync synchronously flushes b, which was mapped using mmap, to disk.
+func msync(b []byte) error {
+	return unix.Msync(b, unix.MS_SYNC)
+}
+
+// munmap unmaps b, which was mapped using mmap.
+func munmap(b []byte) error {
+	return unix.Munmap(b)
+}
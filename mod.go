@@ -3,121 +3,689 @@
 package nicehttp
 
 import (
+	"context"
+	"crypto/cipher"
 	"github.com/valyala/fasthttp"
+	"hash"
 	"io"
+	"sync/atomic"
 	"time"
 )
 
-// defaultClient is a nicehttp.Client with sane configuration defaults.
-var defaultClient = NewClient()
+// defaultClient holds a *Client which all package-level functions route through. It defaults to a nicehttp.Client
+// with sane configuration defaults.
+var defaultClient atomic.Value
+
+func init() {
+	c := NewClient()
+	defaultClient.Store(&c)
+}
+
+// SetDefaultClient has all package-level functions route through a copy of c. It is safe to call concurrently with
+// package-level functions, which use whichever client was set when they were called.
+func SetDefaultClient(c Client) {
+	defaultClient.Store(&c)
+}
+
+// DefaultClient returns a copy of the client all package-level functions route through.
+func DefaultClient() Client {
+	return *getDefaultClient()
+}
+
+// getDefaultClient returns the client all package-level functions route through.
+func getDefaultClient() *Client {
+	return defaultClient.Load().(*Client)
+}
 
 // Do sends a HTTP request prescribed in req and populates its results into res. It additionally handles redirects
 // unlike the de-facto Do(req, res) method in fasthttp.
 func Do(req *fasthttp.Request, res *fasthttp.Response) error {
-	return defaultClient.Do(req, res)
+	return getDefaultClient().Do(req, res)
 }
 
 // DoTimeout sends a HTTP request prescribed in req and populates its results into res. It additionally handles
 // redirects unlike the de-facto Do(req, res) method in fasthttp. It overrides the default timeout set.
 func DoTimeout(req *fasthttp.Request, res *fasthttp.Response, timeout time.Duration) error {
-	return defaultClient.DoTimeout(req, res, timeout)
+	return getDefaultClient().DoTimeout(req, res, timeout)
 }
 
 // DoDeadline sends a HTTP request prescribed in req and populates its results into res. It additionally handles
 // redirects unlike the de-facto Do(req, res) method in fasthttp. It overrides the default timeout set with a deadline.
 func DoDeadline(req *fasthttp.Request, res *fasthttp.Response, deadline time.Time) error {
-	return defaultClient.DoDeadline(req, res, deadline)
+	return getDefaultClient().DoDeadline(req, res, deadline)
 }
 
 // QueryHeaders learns from url its content length, and if it accepts parallel chunk fetching.
 func QueryHeaders(url string) (contentLength int, acceptsRanges bool) {
-	return defaultClient.QueryHeaders(url)
+	return getDefaultClient().QueryHeaders(url)
 }
 
 // QueryHeadersTimeout learns from url its content length, and if it accepts parallel chunk fetching.
 func QueryHeadersTimeout(url string, timeout time.Duration) (contentLength int, acceptsRanges bool) {
-	return defaultClient.QueryHeadersTimeout(url, timeout)
+	return getDefaultClient().QueryHeadersTimeout(url, timeout)
 }
 
 // QueryHeadersDeadline learns from url its content length, and if it accepts parallel chunk fetching.
 func QueryHeadersDeadline(url string, deadline time.Time) (contentLength int, acceptsRanges bool) {
-	return defaultClient.QueryHeadersDeadline(url, deadline)
+	return getDefaultClient().QueryHeadersDeadline(url, deadline)
+}
+
+// QueryHeadersErr learns from url its content length, and if it accepts parallel chunk fetching. It returns an error
+// should the headers of url fail to be queried.
+func QueryHeadersErr(url string) (contentLength int, acceptsRanges bool, err error) {
+	return getDefaultClient().QueryHeadersErr(url)
+}
+
+// QueryHeadersErrTimeout learns from url its content length, and if it accepts parallel chunk fetching. It returns
+// an error should the headers of url fail to be queried.
+func QueryHeadersErrTimeout(url string, timeout time.Duration) (contentLength int, acceptsRanges bool, err error) {
+	return getDefaultClient().QueryHeadersErrTimeout(url, timeout)
+}
+
+// QueryHeadersErrDeadline learns from url its content length, and if it accepts parallel chunk fetching. It returns
+// an error should the headers of url fail to be queried.
+func QueryHeadersErrDeadline(url string, deadline time.Time) (contentLength int, acceptsRanges bool, err error) {
+	return getDefaultClient().QueryHeadersErrDeadline(url, deadline)
+}
+
+// QueryHeadersContext learns from url its content length, and if it accepts parallel chunk fetching. It returns an
+// error should the headers of url fail to be queried, or should ctx be canceled beforehand.
+func QueryHeadersContext(ctx context.Context, url string) (contentLength int, acceptsRanges bool, err error) {
+	return getDefaultClient().QueryHeadersContext(ctx, url)
+}
+
+// QueryInfo learns from url its content length, if it accepts parallel chunk fetching, its content type, its
+// validators, and the URL it redirects to.
+func QueryInfo(url string) (Info, error) {
+	return getDefaultClient().QueryInfo(url)
+}
+
+// QueryInfoTimeout learns from url its content length, if it accepts parallel chunk fetching, its content type, its
+// validators, and the URL it redirects to.
+func QueryInfoTimeout(url string, timeout time.Duration) (Info, error) {
+	return getDefaultClient().QueryInfoTimeout(url, timeout)
+}
+
+// QueryInfoDeadline learns from url its content length, if it accepts parallel chunk fetching, its content type, its
+// validators, and the URL it redirects to.
+func QueryInfoDeadline(url string, deadline time.Time) (Info, error) {
+	return getDefaultClient().QueryInfoDeadline(url, deadline)
 }
 
 // Download downloads the contents of url and writes its contents to w.
 func Download(w Writer, url string, contentLength int, acceptsRanges bool) error {
-	return defaultClient.Download(w, url, contentLength, acceptsRanges)
+	return getDefaultClient().Download(w, url, contentLength, acceptsRanges)
 }
 
 // DownloadTimeout downloads the contents of url and writes its contents to w.
 func DownloadTimeout(w Writer, url string, contentLength int, acceptsRanges bool, timeout time.Duration) error {
-	return defaultClient.DownloadTimeout(w, url, contentLength, acceptsRanges, timeout)
+	return getDefaultClient().DownloadTimeout(w, url, contentLength, acceptsRanges, timeout)
 }
 
 // DownloadDeadline downloads the contents of url and writes its contents to w.
 func DownloadDeadline(w Writer, url string, contentLength int, acceptsRanges bool, deadline time.Time) error {
-	return defaultClient.DownloadDeadline(w, url, contentLength, acceptsRanges, deadline)
+	return getDefaultClient().DownloadDeadline(w, url, contentLength, acceptsRanges, deadline)
 }
 
 // DownloadBytes downloads the contents of url, and returns them as a byte slice.
-func DownloadBytes(dst []byte, url string) ([]byte, error) {
-	return defaultClient.DownloadBytes(dst, url)
+func DownloadBytes(dst []byte, url string, opts ...DownloadOption) ([]byte, error) {
+	return getDefaultClient().DownloadBytes(dst, url, opts...)
 }
 
 // DownloadBytesTimeout downloads the contents of url, and returns them as a byte slice.
-func DownloadBytesTimeout(dst []byte, url string, timeout time.Duration) ([]byte, error) {
-	return defaultClient.DownloadBytesTimeout(dst, url, timeout)
+func DownloadBytesTimeout(dst []byte, url string, timeout time.Duration, opts ...DownloadOption) ([]byte, error) {
+	return getDefaultClient().DownloadBytesTimeout(dst, url, timeout, opts...)
 }
 
 // DownloadBytesDeadline downloads the contents of url, and returns them as a byte slice.
-func DownloadBytesDeadline(dst []byte, url string, deadline time.Time) ([]byte, error) {
-	return defaultClient.DownloadBytesDeadline(dst, url, deadline)
+func DownloadBytesDeadline(dst []byte, url string, deadline time.Time, opts ...DownloadOption) ([]byte, error) {
+	return getDefaultClient().DownloadBytesDeadline(dst, url, deadline, opts...)
+}
+
+// DownloadBytesWithHeader downloads the contents of url, and returns them as a byte slice alongside a copy of the
+// headers of url.
+func DownloadBytesWithHeader(dst []byte, url string) ([]byte, *fasthttp.ResponseHeader, error) {
+	return getDefaultClient().DownloadBytesWithHeader(dst, url)
+}
+
+// DownloadBytesWithHeaderTimeout downloads the contents of url, and returns them as a byte slice alongside a copy of
+// the headers of url.
+func DownloadBytesWithHeaderTimeout(dst []byte, url string, timeout time.Duration) ([]byte, *fasthttp.ResponseHeader, error) {
+	return getDefaultClient().DownloadBytesWithHeaderTimeout(dst, url, timeout)
+}
+
+// DownloadBytesWithHeaderDeadline downloads the contents of url, and returns them as a byte slice alongside a copy of
+// the headers of url.
+func DownloadBytesWithHeaderDeadline(dst []byte, url string, deadline time.Time) ([]byte, *fasthttp.ResponseHeader, error) {
+	return getDefaultClient().DownloadBytesWithHeaderDeadline(dst, url, deadline)
 }
 
 // DownloadFile downloads of url, and writes its contents to a newly-created file titled filename.
-func DownloadFile(filename, url string) error {
-	return defaultClient.DownloadFile(filename, url)
+func DownloadFile(filename, url string, opts ...DownloadOption) error {
+	return getDefaultClient().DownloadFile(filename, url, opts...)
+}
+
+// DownloadFileWithin downloads the contents of url, and writes its contents to a newly-created file titled filename
+// within a total wall-clock budget shared across all requests made.
+func DownloadFileWithin(filename, url string, total time.Duration, opts ...DownloadOption) error {
+	return getDefaultClient().DownloadFileWithin(filename, url, total, opts...)
 }
 
 // DownloadFileTimeout downloads of url, and writes its contents to a newly-created file titled filename.
-func DownloadFileTimeout(filename, url string, timeout time.Duration) error {
-	return defaultClient.DownloadFileTimeout(filename, url, timeout)
+func DownloadFileTimeout(filename, url string, timeout time.Duration, opts ...DownloadOption) error {
+	return getDefaultClient().DownloadFileTimeout(filename, url, timeout, opts...)
 }
 
 // DownloadFileDeadline downloads of url, and writes its contents to a newly-created file titled filename.
-func DownloadFileDeadline(filename, url string, deadline time.Time) error {
-	return defaultClient.DownloadFileDeadline(filename, url, deadline)
+func DownloadFileDeadline(filename, url string, deadline time.Time, opts ...DownloadOption) error {
+	return getDefaultClient().DownloadFileDeadline(filename, url, deadline, opts...)
+}
+
+// DownloadFileSized downloads the contents of url comprised of contentLength bytes without querying its headers
+// beforehand, and writes its contents to a newly-created file titled filename.
+func DownloadFileSized(filename, url string, contentLength int, acceptsRanges bool, opts ...DownloadOption) error {
+	return getDefaultClient().DownloadFileSized(filename, url, contentLength, acceptsRanges, opts...)
+}
+
+// DownloadFileSizedTimeout downloads the contents of url comprised of contentLength bytes without querying its
+// headers beforehand, and writes its contents to a newly-created file titled filename.
+func DownloadFileSizedTimeout(filename, url string, contentLength int, acceptsRanges bool, timeout time.Duration, opts ...DownloadOption) error {
+	return getDefaultClient().DownloadFileSizedTimeout(filename, url, contentLength, acceptsRanges, timeout, opts...)
+}
+
+// DownloadFileSizedDeadline downloads the contents of url comprised of contentLength bytes without querying its
+// headers beforehand, and writes its contents to a newly-created file titled filename.
+func DownloadFileSizedDeadline(filename, url string, contentLength int, acceptsRanges bool, deadline time.Time, opts ...DownloadOption) error {
+	return getDefaultClient().DownloadFileSizedDeadline(filename, url, contentLength, acceptsRanges, deadline, opts...)
+}
+
+// DownloadFileHashed serially downloads the contents of url, and writes its contents to both a newly-created file
+// titled filename and h. It returns the digest computed by h. It returns an error unless the default client has
+// AcceptsRanges disabled, which may be done using SetDefaultClient.
+func DownloadFileHashed(filename, url string, h hash.Hash) ([]byte, error) {
+	return getDefaultClient().DownloadFileHashed(filename, url, h)
+}
+
+// DownloadFileHashedTimeout serially downloads the contents of url, and writes its contents to both a newly-created
+// file titled filename and h. It returns the digest computed by h.
+func DownloadFileHashedTimeout(filename, url string, h hash.Hash, timeout time.Duration) ([]byte, error) {
+	return getDefaultClient().DownloadFileHashedTimeout(filename, url, h, timeout)
+}
+
+// DownloadFileHashedDeadline serially downloads the contents of url, and writes its contents to both a newly-created
+// file titled filename and h. It returns the digest computed by h.
+func DownloadFileHashedDeadline(filename, url string, h hash.Hash, deadline time.Time) ([]byte, error) {
+	return getDefaultClient().DownloadFileHashedDeadline(filename, url, h, deadline)
+}
+
+// DownloadFileTo downloads the contents of url, and writes its contents to a newly-created file in directory dir named
+// after the filename suggested by url. It returns the path to the file.
+func DownloadFileTo(dir, url string, opts ...DownloadOption) (string, error) {
+	return getDefaultClient().DownloadFileTo(dir, url, opts...)
+}
+
+// DownloadFileToTimeout downloads the contents of url, and writes its contents to a newly-created file in directory
+// dir named after the filename suggested by url. It returns the path to the file.
+func DownloadFileToTimeout(dir, url string, timeout time.Duration, opts ...DownloadOption) (string, error) {
+	return getDefaultClient().DownloadFileToTimeout(dir, url, timeout, opts...)
+}
+
+// DownloadFileToDeadline downloads the contents of url, and writes its contents to a newly-created file in directory
+// dir named after the filename suggested by url. It returns the path to the file.
+func DownloadFileToDeadline(dir, url string, deadline time.Time, opts ...DownloadOption) (string, error) {
+	return getDefaultClient().DownloadFileToDeadline(dir, url, deadline, opts...)
+}
+
+// DownloadFileEvents downloads the contents of url in the background, and writes its contents to a newly-created file
+// titled filename. Events describing the state of the download are sent to the returned channel.
+func DownloadFileEvents(filename, url string) (<-chan DownloadEvent, error) {
+	return getDefaultClient().DownloadFileEvents(filename, url)
+}
+
+// DownloadFileEventsTimeout downloads the contents of url in the background, and writes its contents to a
+// newly-created file titled filename. Events describing the state of the download are sent to the returned channel.
+func DownloadFileEventsTimeout(filename, url string, timeout time.Duration) (<-chan DownloadEvent, error) {
+	return getDefaultClient().DownloadFileEventsTimeout(filename, url, timeout)
+}
+
+// DownloadFileEventsDeadline downloads the contents of url in the background, and writes its contents to a
+// newly-created file titled filename. Events describing the state of the download are sent to the returned channel.
+func DownloadFileEventsDeadline(filename, url string, deadline time.Time) (<-chan DownloadEvent, error) {
+	return getDefaultClient().DownloadFileEventsDeadline(filename, url, deadline)
+}
+
+// DownloadFileResumable downloads the contents of url into filename+".part", and renames it to filename once the
+// download has completed. A failed download may be resumed by calling it again.
+func DownloadFileResumable(filename, url string) error {
+	return getDefaultClient().DownloadFileResumable(filename, url)
+}
+
+// DownloadFileResumableTimeout downloads the contents of url into filename+".part", and renames it to filename once
+// the download has completed. A failed download may be resumed by calling it again.
+func DownloadFileResumableTimeout(filename, url string, timeout time.Duration) error {
+	return getDefaultClient().DownloadFileResumableTimeout(filename, url, timeout)
+}
+
+// DownloadFileResumableDeadline downloads the contents of url into filename+".part", and renames it to filename once
+// the download has completed. A failed download may be resumed by calling it again.
+func DownloadFileResumableDeadline(filename, url string, deadline time.Time) error {
+	return getDefaultClient().DownloadFileResumableDeadline(filename, url, deadline)
+}
+
+// ResumeDownload downloads the contents of state.URL into w, skipping over the chunks that state records as having
+// already been downloaded, and records into state every chunk that is downloaded.
+func ResumeDownload(w Writer, state *DownloadState) error {
+	return getDefaultClient().ResumeDownload(w, state)
+}
+
+// ResumeDownloadTimeout downloads the contents of state.URL into w, skipping over the chunks that state records as
+// having already been downloaded, and records into state every chunk that is downloaded.
+func ResumeDownloadTimeout(w Writer, state *DownloadState, timeout time.Duration) error {
+	return getDefaultClient().ResumeDownloadTimeout(w, state, timeout)
+}
+
+// ResumeDownloadDeadline downloads the contents of state.URL into w, skipping over the chunks that state records as
+// having already been downloaded, and records into state every chunk that is downloaded.
+func ResumeDownloadDeadline(w Writer, state *DownloadState, deadline time.Time) error {
+	return getDefaultClient().ResumeDownloadDeadline(w, state, deadline)
+}
+
+// DownloadFiles downloads the contents of the URLs of jobs into their respective files. It returns the errors
+// encountered by each job, in the same order as jobs.
+func DownloadFiles(jobs []DownloadJob) []error {
+	return getDefaultClient().DownloadFiles(jobs)
+}
+
+// DownloadFilesTimeout downloads the contents of the URLs of jobs into their respective files. It returns the errors
+// encountered by each job, in the same order as jobs.
+func DownloadFilesTimeout(jobs []DownloadJob, timeout time.Duration) []error {
+	return getDefaultClient().DownloadFilesTimeout(jobs, timeout)
+}
+
+// DownloadFilesDeadline downloads the contents of the URLs of jobs into their respective files. It returns the errors
+// encountered by each job, in the same order as jobs.
+func DownloadFilesDeadline(jobs []DownloadJob, deadline time.Time) []error {
+	return getDefaultClient().DownloadFilesDeadline(jobs, deadline)
 }
 
 // DownloadSerially contents of url and writes it to w.
 func DownloadSerially(w io.Writer, url string) error {
-	return defaultClient.DownloadSerially(w, url)
+	return getDefaultClient().DownloadSerially(w, url)
 }
 
 // DownloadSeriallyTimeout contents of url and writes it to w.
 func DownloadSeriallyTimeout(w io.Writer, url string, timeout time.Duration) error {
-	return defaultClient.DownloadSeriallyTimeout(w, url, timeout)
+	return getDefaultClient().DownloadSeriallyTimeout(w, url, timeout)
 }
 
 // DownloadSeriallyDeadline contents of url and writes it to w.
 func DownloadSeriallyDeadline(w io.Writer, url string, deadline time.Time) error {
-	return defaultClient.DownloadSeriallyDeadline(w, url, deadline)
+	return getDefaultClient().DownloadSeriallyDeadline(w, url, deadline)
+}
+
+// DownloadSeriallyN serially downloads the contents of url and writes it to w. It returns the number of bytes written
+// to w.
+func DownloadSeriallyN(w io.Writer, url string) (int64, error) {
+	return getDefaultClient().DownloadSeriallyN(w, url)
+}
+
+// DownloadSeriallyNTimeout serially downloads the contents of url and writes it to w. It returns the number of bytes
+// written to w.
+func DownloadSeriallyNTimeout(w io.Writer, url string, timeout time.Duration) (int64, error) {
+	return getDefaultClient().DownloadSeriallyNTimeout(w, url, timeout)
+}
+
+// DownloadSeriallyNDeadline serially downloads the contents of url and writes it to w. It returns the number of bytes
+// written to w.
+func DownloadSeriallyNDeadline(w io.Writer, url string, deadline time.Time) (int64, error) {
+	return getDefaultClient().DownloadSeriallyNDeadline(w, url, deadline)
 }
 
 // DownloadInChunks downloads file at url comprised of length bytes in chunks using multiple workers, and stores it in
 // writer w.
 func DownloadInChunks(w io.WriterAt, url string, length int) error {
-	return defaultClient.DownloadInChunks(w, url, length)
+	return getDefaultClient().DownloadInChunks(w, url, length)
 }
 
 // DownloadInChunksTimeout downloads file at url comprised of length bytes in chunks using multiple workers, and stores
 // it in writer w.
 func DownloadInChunksTimeout(w io.WriterAt, url string, length int, timeout time.Duration) error {
-	return defaultClient.DownloadInChunksTimeout(w, url, length, timeout)
+	return getDefaultClient().DownloadInChunksTimeout(w, url, length, timeout)
 }
 
 // DownloadInChunksDeadline downloads file at url comprised of length bytes in chunks using multiple workers, and
 // stores it in writer w.
 func DownloadInChunksDeadline(w io.WriterAt, url string, length int, deadline time.Time) error {
-	return defaultClient.DownloadInChunksDeadline(w, url, length, deadline)
+	return getDefaultClient().DownloadInChunksDeadline(w, url, length, deadline)
+}
+
+// DownloadInChunksN downloads file at url comprised of length bytes in chunks using multiple workers, and stores it in
+// writer w. It returns the number of bytes written to w across all workers.
+func DownloadInChunksN(w io.WriterAt, url string, length int) (int64, error) {
+	return getDefaultClient().DownloadInChunksN(w, url, length)
+}
+
+// DownloadInChunksNTimeout downloads file at url comprised of length bytes in chunks using multiple workers, and
+// stores it in writer w. It returns the number of bytes written to w across all workers.
+func DownloadInChunksNTimeout(w io.WriterAt, url string, length int, timeout time.Duration) (int64, error) {
+	return getDefaultClient().DownloadInChunksNTimeout(w, url, length, timeout)
+}
+
+// DownloadInChunksNDeadline downloads file at url comprised of length bytes in chunks using multiple workers, and
+// stores it in writer w. It returns the number of bytes written to w across all workers.
+func DownloadInChunksNDeadline(w io.WriterAt, url string, length int, deadline time.Time) (int64, error) {
+	return getDefaultClient().DownloadInChunksNDeadline(w, url, length, deadline)
+}
+
+// DownloadDecrypt serially downloads the contents of url, decrypts them using block in CTR mode with initialization
+// vector iv as they are written, and writes the plaintext to w.
+func DownloadDecrypt(w io.Writer, url string, block cipher.Block, iv []byte) error {
+	return getDefaultClient().DownloadDecrypt(w, url, block, iv)
+}
+
+// DownloadDecryptTimeout serially downloads the contents of url, decrypts them using block in CTR mode with
+// initialization vector iv as they are written, and writes the plaintext to w.
+func DownloadDecryptTimeout(w io.Writer, url string, block cipher.Block, iv []byte, timeout time.Duration) error {
+	return getDefaultClient().DownloadDecryptTimeout(w, url, block, iv, timeout)
+}
+
+// DownloadDecryptDeadline serially downloads the contents of url, decrypts them using block in CTR mode with
+// initialization vector iv as they are written, and writes the plaintext to w.
+func DownloadDecryptDeadline(w io.Writer, url string, block cipher.Block, iv []byte, deadline time.Time) error {
+	return getDefaultClient().DownloadDecryptDeadline(w, url, block, iv, deadline)
+}
+
+// DownloadDecryptCBC serially downloads the contents of url, decrypts them using block in CBC mode with initialization
+// vector iv as they are written, and writes the plaintext to w.
+func DownloadDecryptCBC(w io.Writer, url string, block cipher.Block, iv []byte) error {
+	return getDefaultClient().DownloadDecryptCBC(w, url, block, iv)
+}
+
+// DownloadDecryptCBCTimeout serially downloads the contents of url, decrypts them using block in CBC mode with
+// initialization vector iv as they are written, and writes the plaintext to w.
+func DownloadDecryptCBCTimeout(w io.Writer, url string, block cipher.Block, iv []byte, timeout time.Duration) error {
+	return getDefaultClient().DownloadDecryptCBCTimeout(w, url, block, iv, timeout)
+}
+
+// DownloadDecryptCBCDeadline serially downloads the contents of url, decrypts them using block in CBC mode with
+// initialization vector iv as they are written, and writes the plaintext to w.
+func DownloadDecryptCBCDeadline(w io.Writer, url string, block cipher.Block, iv []byte, deadline time.Time) error {
+	return getDefaultClient().DownloadDecryptCBCDeadline(w, url, block, iv, deadline)
+}
+
+// DownloadFromMirrors downloads the contents comprised of length bytes served by each of urls in chunks using
+// multiple workers, and stores it in writer f.
+func DownloadFromMirrors(f io.WriterAt, urls []string, length int) error {
+	return getDefaultClient().DownloadFromMirrors(f, urls, length)
+}
+
+// DownloadFromMirrorsTimeout downloads the contents comprised of length bytes served by each of urls in chunks using
+// multiple workers, and stores it in writer f.
+func DownloadFromMirrorsTimeout(f io.WriterAt, urls []string, length int, timeout time.Duration) error {
+	return getDefaultClient().DownloadFromMirrorsTimeout(f, urls, length, timeout)
+}
+
+// DownloadFromMirrorsDeadline downloads the contents comprised of length bytes served by each of urls in chunks using
+// multiple workers, and stores it in writer f.
+func DownloadFromMirrorsDeadline(f io.WriterAt, urls []string, length int, deadline time.Time) error {
+	return getDefaultClient().DownloadFromMirrorsDeadline(f, urls, length, deadline)
+}
+
+// DownloadStream downloads the contents of url comprised of length bytes in chunks using multiple workers, and writes
+// them to w in order.
+func DownloadStream(w io.Writer, url string, length int) error {
+	return getDefaultClient().DownloadStream(w, url, length)
+}
+
+// DownloadStreamTimeout downloads the contents of url comprised of length bytes in chunks using multiple workers,
+// and writes them to w in order.
+func DownloadStreamTimeout(w io.Writer, url string, length int, timeout time.Duration) error {
+	return getDefaultClient().DownloadStreamTimeout(w, url, length, timeout)
+}
+
+// DownloadStreamDeadline downloads the contents of url comprised of length bytes in chunks using multiple workers,
+// and writes them to w in order.
+func DownloadStreamDeadline(w io.Writer, url string, length int, deadline time.Time) error {
+	return getDefaultClient().DownloadStreamDeadline(w, url, length, deadline)
+}
+
+// DownloadSmart downloads the contents of url into w without first querying its headers, and downloads the rest of
+// its contents in chunks should they be larger than the first chunk.
+func DownloadSmart(w Writer, url string) error {
+	return getDefaultClient().DownloadSmart(w, url)
+}
+
+// DownloadSmartTimeout downloads the contents of url into w without first querying its headers, and downloads the
+// rest of its contents in chunks should they be larger than the first chunk.
+func DownloadSmartTimeout(w Writer, url string, timeout time.Duration) error {
+	return getDefaultClient().DownloadSmartTimeout(w, url, timeout)
+}
+
+// DownloadSmartDeadline downloads the contents of url into w without first querying its headers, and downloads the
+// rest of its contents in chunks should they be larger than the first chunk.
+func DownloadSmartDeadline(w Writer, url string, deadline time.Time) error {
+	return getDefaultClient().DownloadSmartDeadline(w, url, deadline)
+}
+
+// ProbeContentLength learns the content length of url by requesting its first byte, and reading the total length of
+// its contents from the Content-Range header of the response.
+func ProbeContentLength(url string) (int, error) {
+	return getDefaultClient().ProbeContentLength(url)
+}
+
+// ProbeContentLengthTimeout learns the content length of url by requesting its first byte, and reading the total
+// length of its contents from the Content-Range header of the response.
+func ProbeContentLengthTimeout(url string, timeout time.Duration) (int, error) {
+	return getDefaultClient().ProbeContentLengthTimeout(url, timeout)
+}
+
+// ProbeContentLengthDeadline learns the content length of url by requesting its first byte, and reading the total
+// length of its contents from the Content-Range header of the response.
+func ProbeContentLengthDeadline(url string, deadline time.Time) (int, error) {
+	return getDefaultClient().ProbeContentLengthDeadline(url, deadline)
+}
+
+// DownloadRanges downloads only the given byte ranges of the contents of url using multiple workers, and stores them
+// in writer f.
+func DownloadRanges(f io.WriterAt, url string, ranges []ByteRange) error {
+	return getDefaultClient().DownloadRanges(f, url, ranges)
+}
+
+// DownloadRangesTimeout downloads only the given byte ranges of the contents of url using multiple workers, and
+// stores them in writer f.
+func DownloadRangesTimeout(f io.WriterAt, url string, ranges []ByteRange, timeout time.Duration) error {
+	return getDefaultClient().DownloadRangesTimeout(f, url, ranges, timeout)
+}
+
+// DownloadRangesDeadline downloads only the given byte ranges of the contents of url using multiple workers, and
+// stores them in writer f.
+func DownloadRangesDeadline(f io.WriterAt, url string, ranges []ByteRange, deadline time.Time) error {
+	return getDefaultClient().DownloadRangesDeadline(f, url, ranges, deadline)
+}
+
+// PostBytes sends a POST request to url with body of type contentType, and returns the body of the response.
+func PostBytes(url, contentType string, body []byte) ([]byte, error) {
+	return getDefaultClient().PostBytes(url, contentType, body)
+}
+
+// PostBytesTimeout sends a POST request to url with body of type contentType, and returns the body of the response.
+func PostBytesTimeout(url, contentType string, body []byte, timeout time.Duration) ([]byte, error) {
+	return getDefaultClient().PostBytesTimeout(url, contentType, body, timeout)
+}
+
+// PostBytesDeadline sends a POST request to url with body of type contentType, and returns the body of the response.
+func PostBytesDeadline(url, contentType string, body []byte, deadline time.Time) ([]byte, error) {
+	return getDefaultClient().PostBytesDeadline(url, contentType, body, deadline)
+}
+
+// PutBytes sends a PUT request to url with body of type contentType, and returns the body of the response.
+func PutBytes(url, contentType string, body []byte) ([]byte, error) {
+	return getDefaultClient().PutBytes(url, contentType, body)
+}
+
+// PutBytesTimeout sends a PUT request to url with body of type contentType, and returns the body of the response.
+func PutBytesTimeout(url, contentType string, body []byte, timeout time.Duration) ([]byte, error) {
+	return getDefaultClient().PutBytesTimeout(url, contentType, body, timeout)
+}
+
+// PutBytesDeadline sends a PUT request to url with body of type contentType, and returns the body of the response.
+func PutBytesDeadline(url, contentType string, body []byte, deadline time.Time) ([]byte, error) {
+	return getDefaultClient().PutBytesDeadline(url, contentType, body, deadline)
+}
+
+// DownloadJSON downloads the contents of url, and unmarshals them as JSON into v.
+func DownloadJSON(url string, v interface{}) error {
+	return getDefaultClient().DownloadJSON(url, v)
+}
+
+// DownloadJSONTimeout downloads the contents of url, and unmarshals them as JSON into v.
+func DownloadJSONTimeout(url string, v interface{}, timeout time.Duration) error {
+	return getDefaultClient().DownloadJSONTimeout(url, v, timeout)
+}
+
+// DownloadJSONDeadline downloads the contents of url, and unmarshals them as JSON into v.
+func DownloadJSONDeadline(url string, v interface{}, deadline time.Time) error {
+	return getDefaultClient().DownloadJSONDeadline(url, v, deadline)
+}
+
+// UploadFile uploads the file titled filename to url as a multipart/form-data POST request, under the form field
+// fieldName alongside fields.
+func UploadFile(url, fieldName, filename string, fields ...FormField) error {
+	return getDefaultClient().UploadFile(url, fieldName, filename, fields...)
+}
+
+// UploadFileTimeout uploads the file titled filename to url as a multipart/form-data POST request, under the form
+// field fieldName alongside fields.
+func UploadFileTimeout(url, fieldName, filename string, timeout time.Duration, fields ...FormField) error {
+	return getDefaultClient().UploadFileTimeout(url, fieldName, filename, timeout, fields...)
+}
+
+// UploadFileDeadline uploads the file titled filename to url as a multipart/form-data POST request, under the form
+// field fieldName alongside fields.
+func UploadFileDeadline(url, fieldName, filename string, deadline time.Time, fields ...FormField) error {
+	return getDefaultClient().UploadFileDeadline(url, fieldName, filename, deadline, fields...)
+}
+
+// Open downloads the contents of url in the background, following its redirects, and returns a reader which yields
+// the contents as they are downloaded. The reader must be closed once it is no longer needed. Contents that may not
+// be downloaded in chunks are received in their entirety before Open returns.
+func Open(url string) (io.ReadCloser, error) {
+	return getDefaultClient().Open(url)
+}
+
+// OpenTimeout downloads the contents of url in the background, following its redirects, and returns a reader which
+// yields the contents as they are downloaded.
+func OpenTimeout(url string, timeout time.Duration) (io.ReadCloser, error) {
+	return getDefaultClient().OpenTimeout(url, timeout)
+}
+
+// OpenDeadline downloads the contents of url in the background, following its redirects, and returns a reader which
+// yields the contents as they are downloaded.
+func OpenDeadline(url string, deadline time.Time) (io.ReadCloser, error) {
+	return getDefaultClient().OpenDeadline(url, deadline)
+}
+
+// Prewarm opens n connections to the host of url ahead of a download by sending n HEAD requests to url at once.
+func Prewarm(url string, n int) error {
+	return getDefaultClient().Prewarm(url, n)
+}
+
+// PrewarmTimeout opens n connections to the host of url ahead of a download by sending n HEAD requests to url at once.
+func PrewarmTimeout(url string, n int, timeout time.Duration) error {
+	return getDefaultClient().PrewarmTimeout(url, n, timeout)
+}
+
+// PrewarmDeadline opens n connections to the host of url ahead of a download by sending n HEAD requests to url at
+// once.
+func PrewarmDeadline(url string, n int, deadline time.Time) error {
+	return getDefaultClient().PrewarmDeadline(url, n, deadline)
+}
+
+// StartDownloadFile starts downloading the contents of url into a newly-created file titled filename in the
+// background, and returns a handle to the download which may be used to cancel it, or to wait for it to complete.
+func StartDownloadFile(filename, url string, opts ...DownloadOption) *DownloadHandle {
+	return getDefaultClient().StartDownloadFile(filename, url, opts...)
+}
+
+// StartDownloadFileTimeout starts downloading the contents of url into a newly-created file titled filename in the
+// background, and returns a handle to the download.
+func StartDownloadFileTimeout(filename, url string, timeout time.Duration, opts ...DownloadOption) *DownloadHandle {
+	return getDefaultClient().StartDownloadFileTimeout(filename, url, timeout, opts...)
+}
+
+// StartDownloadFileDeadline starts downloading the contents of url into a newly-created file titled filename in the
+// background, and returns a handle to the download.
+func StartDownloadFileDeadline(filename, url string, deadline time.Time, opts ...DownloadOption) *DownloadHandle {
+	return getDefaultClient().StartDownloadFileDeadline(filename, url, deadline, opts...)
+}
+
+// Plan queries the headers of url, and returns how its contents would be downloaded without downloading any of them.
+func Plan(url string) (DownloadPlan, error) {
+	return getDefaultClient().Plan(url)
+}
+
+// PlanTimeout queries the headers of url, and returns how its contents would be downloaded without downloading any
+// of them.
+func PlanTimeout(url string, timeout time.Duration) (DownloadPlan, error) {
+	return getDefaultClient().PlanTimeout(url, timeout)
+}
+
+// PlanDeadline queries the headers of url, and returns how its contents would be downloaded without downloading any
+// of them.
+func PlanDeadline(url string, deadline time.Time) (DownloadPlan, error) {
+	return getDefaultClient().PlanDeadline(url, deadline)
+}
+
+// PlanAll plans the download of the contents of each of urls, and returns their plans alongside the total length of
+// their contents.
+func PlanAll(urls []string) BatchPlan {
+	return getDefaultClient().PlanAll(urls)
+}
+
+// PlanAllTimeout plans the download of the contents of each of urls, and returns their plans alongside the total
+// length of their contents.
+func PlanAllTimeout(urls []string, timeout time.Duration) BatchPlan {
+	return getDefaultClient().PlanAllTimeout(urls, timeout)
+}
+
+// PlanAllDeadline plans the download of the contents of each of urls, and returns their plans alongside the total
+// length of their contents.
+func PlanAllDeadline(urls []string, deadline time.Time) BatchPlan {
+	return getDefaultClient().PlanAllDeadline(urls, deadline)
+}
+
+// DownloadRange downloads the half-open byte range [start, end) of the contents of url using a single request, and
+// writes it to w. It returns the number of bytes written to w.
+func DownloadRange(w io.Writer, url string, start, end int) (int, error) {
+	return getDefaultClient().DownloadRange(w, url, start, end)
+}
+
+// DownloadRangeTimeout downloads the half-open byte range [start, end) of the contents of url using a single request,
+// and writes it to w. It returns the number of bytes written to w.
+func DownloadRangeTimeout(w io.Writer, url string, start, end int, timeout time.Duration) (int, error) {
+	return getDefaultClient().DownloadRangeTimeout(w, url, start, end, timeout)
+}
+
+// DownloadRangeDeadline downloads the half-open byte range [start, end) of the contents of url using a single
+// request, and writes it to w. It returns the number of bytes written to w.
+func DownloadRangeDeadline(w io.Writer, url string, start, end int, deadline time.Time) (int, error) {
+	return getDefaultClient().DownloadRangeDeadline(w, url, start, end, deadline)
+}
+
+// OpenStream downloads the contents of url in chunks using multiple workers in the background, and returns a reader
+// which yields the contents in order as the chunks before them complete.
+func OpenStream(url string) (io.ReadCloser, error) {
+	return getDefaultClient().OpenStream(url)
+}
+
+// OpenStreamTimeout downloads the contents of url in chunks using multiple workers in the background, and returns a
+// reader which yields the contents in order.
+func OpenStreamTimeout(url string, timeout time.Duration) (io.ReadCloser, error) {
+	return getDefaultClient().OpenStreamTimeout(url, timeout)
+}
+
+// OpenStreamDeadline downloads the contents of url in chunks using multiple workers in the background, and returns a
+// reader which yields the contents in order.
+func OpenStreamDeadline(url string, deadline time.Time) (io.ReadCloser, error) {
+	return getDefaultClient().OpenStreamDeadline(url, deadline)
 }
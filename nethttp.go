@@ -0,0 +1,165 @@
+package nicehttp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var _ Transport = (*NetHTTPTransport)(nil)
+
+// NetHTTPTransport is a Transport which sends requests using a *net/http.Client, such that requests may be sent over
+// HTTP/2, which fasthttp does not support. With HTTP/2, requests for chunks made by all workers of a download are
+// multiplexed over a single connection to a host rather than each being sent over a connection of their own.
+//
+// The *http.Transport that net/http uses by default negotiates HTTP/2 over TLS on its own. Servers that speak HTTP/2
+// over cleartext (h2c) may be reached by setting the Transport of the *http.Client to a *http2.Transport from
+// golang.org/x/net/http2 with AllowHTTP set, and with DialTLS set to dial plain TCP connections.
+//
+// Redirects are never followed by the *http.Client, as they are followed by the Client using the Transport instead.
+// The *http.Client should not be configured with a cookie jar either; set Client.Jar instead. Options that configure
+// a *fasthttp.Client, such as WithDial, WithProxy, WithTLSConfig and WithMaxConnsPerHost, as well as
+// PinToResolvedIP, have no effect, and are to be configured on the *http.Client instead.
+//
+// As the requests of a download are multiplexed over a single connection, NumWorkers only bounds how many chunks are
+// in flight at once rather than how many connections are opened. Fewer workers than with HTTP/1.1, such as 4, are
+// typically enough to saturate a connection, while more workers mostly contend for the same connection. The default
+// NumWorkers of a Client is left as-is regardless of its Transport, and should be lowered explicitly using
+// WithNumWorkers should a Client use a NetHTTPTransport.
+type NetHTTPTransport struct {
+	// The client requests are sent using. Defaults to http.DefaultClient should it be nil.
+	Client *http.Client
+}
+
+// NewNetHTTPTransport instantiates a Transport which sends requests using client.
+func NewNetHTTPTransport(client *http.Client) *NetHTTPTransport {
+	return &NetHTTPTransport{Client: client}
+}
+
+// Do implements Transport.
+func (t *NetHTTPTransport) Do(req *fasthttp.Request, res *fasthttp.Response) error {
+	return t.do(context.Background(), req, res)
+}
+
+// DoTimeout implements Transport.
+func (t *NetHTTPTransport) DoTimeout(req *fasthttp.Request, res *fasthttp.Response, timeout time.Duration) error {
+	return t.DoDeadline(req, res, time.Now().Add(timeout))
+}
+
+// DoDeadline implements Transport.
+func (t *NetHTTPTransport) DoDeadline(req *fasthttp.Request, res *fasthttp.Response, deadline time.Time) error {
+	if deadline.IsZero() {
+		return t.Do(req, res)
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	return t.do(ctx, req, res)
+}
+
+// do sends req using t.Client, and populates the response into res. The body of the response is read entirely into
+// res before do returns, the same way fasthttp does.
+func (t *NetHTTPTransport) do(ctx context.Context, req *fasthttp.Request, res *fasthttp.Response) error {
+	client := http.DefaultClient
+	if t.Client != nil {
+		client = t.Client
+	}
+
+	hc := *client
+	hc.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+
+	var body io.Reader
+
+	if req.IsBodyStream() {
+		r, w := io.Pipe()
+		defer r.Close()
+
+		go func() { w.CloseWithError(req.BodyWriteTo(w)) }()
+
+		body = r
+	} else if b := req.Body(); len(b) > 0 {
+		body = bytes.NewReader(b)
+	}
+
+	hreq, err := http.NewRequestWithContext(ctx, string(req.Header.Method()), req.URI().String(), body)
+	if err != nil {
+		return fmt.Errorf("failed to convert request: %w", err)
+	}
+
+	if req.IsBodyStream() {
+		hreq.ContentLength = int64(req.Header.ContentLength())
+		if hreq.ContentLength < 0 {
+			hreq.ContentLength = -1
+		}
+	}
+
+	req.Header.VisitAll(func(key, value []byte) {
+		switch k := string(key); strings.ToLower(k) {
+		case "host":
+			hreq.Host = string(value)
+		case "content-length", "connection", "transfer-encoding":
+		default:
+			hreq.Header.Add(k, string(value))
+		}
+	})
+
+	hres, err := hc.Do(hreq)
+	if err != nil {
+		return netHTTPError(err)
+	}
+	defer hres.Body.Close()
+
+	res.Reset()
+	res.SetStatusCode(hres.StatusCode)
+
+	// Headers such as Content-Type and Set-Cookie are only parsed by fasthttp should they be set using Set.
+
+	for key, values := range hres.Header {
+		if strings.EqualFold(key, "Content-Length") {
+			continue
+		}
+
+		for i, value := range values {
+			if i == 0 || strings.EqualFold(key, "Set-Cookie") {
+				res.Header.Set(key, value)
+			} else {
+				res.Header.Add(key, value)
+			}
+		}
+	}
+
+	if req.Header.IsHead() {
+		if hres.ContentLength >= 0 {
+			res.Header.SetContentLength(int(hres.ContentLength))
+		}
+		return nil
+	}
+
+	n, err := io.Copy(res.BodyWriter(), hres.Body)
+	if err != nil {
+		return netHTTPError(err)
+	}
+
+	res.Header.SetContentLength(int(n))
+
+	return nil
+}
+
+// netHTTPError maps err, as returned by net/http, onto the errors returned by fasthttp for the same failures.
+func netHTTPError(err error) error {
+	var netErr net.Error
+
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return fasthttp.ErrTimeout
+	}
+
+	return err
+}
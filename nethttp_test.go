@@ -0,0 +1,185 @@
+package nicehttp
+
+import (
+	"bytes"
+	"errors"
+	"github.com/valyala/fasthttp"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNetHTTPTransportMultiplexesChunks(t *testing.T) {
+	contents := testContents(256)
+
+	var (
+		conns int32
+		log   requestLog
+
+		mu     sync.Mutex
+		protos []int
+	)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.record(r)
+
+		mu.Lock()
+		protos = append(protos, r.ProtoMajor)
+		mu.Unlock()
+
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(contents))
+	}))
+	srv.EnableHTTP2 = true
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	c := WrapClient(NewNetHTTPTransport(srv.Client()))
+	c.NumWorkers = 4
+	c.ChunkSize = 8
+
+	dst, err := c.DownloadBytes(nil, srv.URL)
+	if err != nil {
+		t.Fatalf("failed to download: %s", err)
+	}
+	if !bytes.Equal(dst, contents) {
+		t.Fatalf("downloaded contents do not match")
+	}
+
+	var ranged int
+	for _, req := range log.all() {
+		if req.Range != "" {
+			ranged++
+		}
+	}
+	if ranged != len(contents)/8 {
+		t.Fatalf("expected %d byte ranges to be requested, got %d", len(contents)/8, ranged)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, proto := range protos {
+		if proto != 2 {
+			t.Fatalf("expected all requests to be sent over HTTP/2, got HTTP/%d", proto)
+		}
+	}
+
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Fatalf("expected all chunks to be multiplexed over a single connection, got %d connections", n)
+	}
+}
+
+func TestNetHTTPTransportConvertsMessages(t *testing.T) {
+	contents := testContents(64)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			w.Header().Set("X-Content-Length", r.Header.Get("Content-Length"))
+			w.Write(body)
+		default:
+			http.SetCookie(w, &http.Cookie{Name: "a", Value: "1"})
+			http.SetCookie(w, &http.Cookie{Name: "b", Value: "2"})
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(contents))
+		}
+	}))
+	defer srv.Close()
+
+	tr := NewNetHTTPTransport(srv.Client())
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	res := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(res)
+
+	// Every Set-Cookie header is kept, rather than only the first.
+
+	req.SetRequestURI(srv.URL)
+	req.Header.SetMethod(http.MethodGet)
+
+	if err := tr.Do(req, res); err != nil {
+		t.Fatalf("failed to send request: %s", err)
+	}
+	if !bytes.Equal(res.Body(), contents) || res.Header.ContentLength() != len(contents) {
+		t.Fatalf("expected body of %d byte(s), got %d byte(s)", len(contents), len(res.Body()))
+	}
+
+	var cookies []string
+	res.Header.VisitAllCookie(func(key, _ []byte) {
+		cookies = append(cookies, string(key))
+	})
+	if strings.Join(cookies, ",") != "a,b" {
+		t.Fatalf("expected cookies a and b to be set, got %v", cookies)
+	}
+
+	// The content length of a response to a HEAD request is that reported by the server.
+
+	req.Header.SetMethod(http.MethodHead)
+
+	if err := tr.Do(req, res); err != nil {
+		t.Fatalf("failed to send request: %s", err)
+	}
+	if res.Header.ContentLength() != len(contents) || len(res.Body()) != 0 {
+		t.Fatalf("expected content length %d and no body, got %d and %d byte(s)", len(contents), res.Header.ContentLength(), len(res.Body()))
+	}
+
+	// Bodies of requests set as a stream are sent along with their content length.
+
+	req.Header.SetMethod(http.MethodPost)
+	req.SetBodyStream(bytes.NewReader(contents), len(contents))
+
+	if err := tr.Do(req, res); err != nil {
+		t.Fatalf("failed to send request: %s", err)
+	}
+	if !bytes.Equal(res.Body(), contents) {
+		t.Fatalf("expected body stream to be sent")
+	}
+	if got := string(res.Header.Peek("X-Content-Length")); got != "64" {
+		t.Fatalf("expected content length of body stream to be sent, got %q", got)
+	}
+}
+
+func TestNetHTTPTransportTimeout(t *testing.T) {
+	release := make(chan struct{})
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	srv.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	srv.Start()
+	defer srv.Close()
+	defer close(release)
+
+	tr := NewNetHTTPTransport(srv.Client())
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	res := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(res)
+
+	req.SetRequestURI(srv.URL)
+
+	if err := tr.DoTimeout(req, res, 50*time.Millisecond); !errors.Is(err, fasthttp.ErrTimeout) {
+		t.Fatalf("expected fasthttp.ErrTimeout, got %v", err)
+	}
+}
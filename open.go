@@ -0,0 +1,68 @@
+package nicehttp
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// Open downloads the contents of url in the background, following its redirects, and returns a reader which yields
+// the contents as they are downloaded. Contents are downloaded the same way OpenStream downloads them: in chunks using
+// multiple workers should url accept ranges, and serially otherwise, transparently decompressed the same way serial
+// downloads are. The reader must be closed once it is no longer needed, which stops the download should it still be
+// in progress.
+//
+// Unlike OpenStream, Open only returns once either the first bytes of the contents have been downloaded or the
+// download has failed, such that failures to send the request, unexpected status codes and ErrNotModified are
+// returned by Open rather than by the first Read.
+//
+// Before the download starts, a HEAD request is sent to url to decide whether its contents may be downloaded in
+// chunks. Contents that are downloaded in chunks are yielded chunk by chunk as soon as all chunks before them have
+// been downloaded. Contents that are downloaded serially are not streamed however, as fasthttp reads the entirety of
+// a response body into memory before returning it. Open then only returns once the entire body has been received,
+// after which the reader yields it from memory, such that Open is no better than DownloadBytes for such contents.
+func (c *Client) Open(url string) (io.ReadCloser, error) {
+	return c.OpenDeadline(url, zeroTime)
+}
+
+// OpenTimeout downloads the contents of url in the background, following its redirects, and returns a reader which
+// yields the contents as they are downloaded. The timeout bounds both the download and the reads made from the
+// reader.
+func (c *Client) OpenTimeout(url string, timeout time.Duration) (io.ReadCloser, error) {
+	return c.OpenDeadline(url, c.clock().Add(timeout))
+}
+
+// OpenDeadline downloads the contents of url in the background, following its redirects, and returns a reader which
+// yields the contents as they are downloaded. The deadline bounds both the download and the reads made from the
+// reader.
+func (c *Client) OpenDeadline(url string, deadline time.Time) (io.ReadCloser, error) {
+	s, err := c.openStream(url, deadline)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-s.started:
+	case <-s.done:
+		if s.err != nil {
+			s.Close()
+			return nil, s.err
+		}
+	}
+
+	return s, nil
+}
+
+// startWriter is an io.Writer which closes started once it is first written to, before the write is made.
+type startWriter struct {
+	io.Writer
+
+	once    sync.Once
+	started chan struct{}
+}
+
+// Write implements io.Writer.
+func (w *startWriter) Write(b []byte) (int, error) {
+	w.once.Do(func() { close(w.started) })
+	return w.Writer.Write(b)
+}
@@ -0,0 +1,108 @@
+package nicehttp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpenReadsWhileDownloading(t *testing.T) {
+	contents := testContents(64)
+
+	release := make(chan struct{})
+
+	// Only the first chunk is served until it has been read from the reader returned by Open.
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead && r.Header.Get("Range") != "bytes=0-7" {
+			<-release
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(contents))
+	}))
+	defer srv.Close()
+	defer func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+	}()
+
+	c := newTestClient(8)
+
+	type result struct {
+		r   io.ReadCloser
+		buf []byte
+		err error
+	}
+
+	ch := make(chan result, 1)
+
+	go func() {
+		r, err := c.Open(srv.URL)
+		if err != nil {
+			ch <- result{err: err}
+			return
+		}
+
+		buf := make([]byte, 8)
+		_, err = io.ReadFull(r, buf)
+
+		ch <- result{r: r, buf: buf, err: err}
+	}()
+
+	var res result
+
+	select {
+	case res = <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the first chunk to be readable before the download completes")
+	}
+
+	if res.err != nil {
+		t.Fatalf("failed to read first chunk: %s", res.err)
+	}
+	defer res.r.Close()
+
+	if !bytes.Equal(res.buf, contents[:8]) {
+		t.Fatalf("first chunk does not match")
+	}
+
+	close(release)
+
+	rest, err := ioutil.ReadAll(res.r)
+	if err != nil {
+		t.Fatalf("failed to read rest of contents: %s", err)
+	}
+	if !bytes.Equal(rest, contents[8:]) {
+		t.Fatalf("rest of contents do not match")
+	}
+}
+
+func TestOpenReturnsFailures(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/missing") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer srv.Close()
+
+	c := newTestClient(8)
+	c.CheckStatus = true
+
+	if _, err := c.Open(srv.URL + "/missing"); err == nil {
+		t.Fatalf("expected open to fail")
+	}
+
+	if _, err := c.Open(srv.URL); !errors.Is(err, ErrNotModified) {
+		t.Fatalf("expected ErrNotModified, got %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+package nicehttp
+
+import (
+	"crypto"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/sync/semaphore"
+	"hash"
+)
+
+// ErrNotModified is returned by conditional downloads should the contents of a URL not have been modified.
+var ErrNotModified = errors.New("not modified")
+
+// Option configures a Client. Options are applied in order by NewClientWith.
+type Option func(c *Client)
+
+// WithNumWorkers sets the number of workers used to download the contents of a URL in parallel chunks.
+func WithNumWorkers(numWorkers int) Option {
+	return func(c *Client) {
+		c.NumWorkers = numWorkers
+	}
+}
+
+// WithChunkSize sets the size of each chunk the contents of a URL are downloaded in.
+func WithChunkSize(chunkSize int) Option {
+	return func(c *Client) {
+		c.ChunkSize = chunkSize
+	}
+}
+
+// WithCopyBufferSize sets the size of the buffer that the body of a serial download is copied through into its writer.
+func WithCopyBufferSize(size int) Option {
+	return func(c *Client) {
+		c.CopyBufferSize = size
+	}
+}
+
+// WithProbeMethod sets the method of the request made to query the headers of a URL before downloading it.
+func WithProbeMethod(method string) Option {
+	return func(c *Client) {
+		c.ProbeMethod = method
+	}
+}
+
+// WithMaxRedirects sets the max number of redirects that are followed before a request fails.
+func WithMaxRedirects(maxRedirectCount int) Option {
+	return func(c *Client) {
+		c.MaxRedirectCount = maxRedirectCount
+	}
+}
+
+// WithTransport sets the Transport which requests are sent through.
+func WithTransport(t Transport) Option {
+	return func(c *Client) {
+		c.Instance = t
+	}
+}
+
+// WithTLSConfig has all TLS connections made by the Client be configured using config, i.e. to trust a custom CA
+// bundle or to present a client certificate. It only has an effect should the Transport of the Client be a
+// *fasthttp.Client, whose TLSConfig is then replaced by config. Any other Transport, such as a *fasthttp.HostClient,
+// is expected to have its TLSConfig configured instead.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(c *Client) {
+		if instance, ok := c.Instance.(*fasthttp.Client); ok {
+			instance.TLSConfig = config
+		}
+	}
+}
+
+// WithMaxConnsPerHost caps the number of connections the Client keeps open to any single host to n. It only has an
+// effect should the Transport of the Client be a *fasthttp.Client, whose MaxConnsPerHost is then set to n, and is a
+// no-op for any other Transport, which is expected to have its own connection limit configured instead. A value of
+// zero or less restores fasthttp's default of 512 connections per host.
+//
+// Every worker downloading a chunk holds onto a connection for as long as it is downloading said chunk, such that a
+// single download uses up to NumWorkers connections to its host at once, and concurrent downloads from the same host
+// add up. Should n be lower than the number of workers downloading from a host at once, workers beyond n have to
+// wait for a free connection, and are retried up to MaxNoFreeConnsRetries times before their chunk fails.
+func WithMaxConnsPerHost(n int) Option {
+	return func(c *Client) {
+		if instance, ok := c.Instance.(*fasthttp.Client); ok {
+			if n < 0 {
+				n = 0
+			}
+			instance.MaxConnsPerHost = n
+		}
+	}
+}
+
+// WithSemaphore has every request sent by the Client acquire a unit of sem for as long as it is in flight. Sharing sem
+// across multiple clients bounds the number of requests in flight across all of them at once.
+func WithSemaphore(sem *semaphore.Weighted) Option {
+	return func(c *Client) {
+		c.Semaphore = sem
+	}
+}
+
+// WithBasicAuth has every request sent by the Client carry an Authorization header with user and password using the
+// Basic authentication scheme. Like all other credentials, the header is stripped away from requests that follow a
+// redirect to a different host unless KeepSensitiveHeadersOnRedirect is set.
+func WithBasicAuth(user, password string) Option {
+	return func(c *Client) {
+		c.SetHeader("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user+":"+password)))
+	}
+}
+
+// WithBearerToken has every request sent by the Client carry an Authorization header with token using the Bearer
+// authentication scheme. Like WithBasicAuth, the header is stripped away from requests that follow a redirect to a
+// different host.
+func WithBearerToken(token string) Option {
+	return func(c *Client) {
+		c.SetHeader("Authorization", "Bearer "+token)
+	}
+}
+
+// WithClock has the Client read the current time from clock, and wait out backoff and throttling delays against it.
+// A HostCircuitBreaker reads the current time from its own Clock instead.
+func WithClock(clock Clock) Option {
+	return func(c *Client) {
+		c.clk = clock
+	}
+}
+
+// DownloadOption configures a single download.
+type DownloadOption func(o *downloadOptions)
+
+// downloadOptions are the options of a single download.
+type downloadOptions struct {
+	checksumHash   crypto.Hash
+	checksumWant   []byte
+	checksumResult *ChecksumResult
+
+	ifNoneMatch     string
+	ifModifiedSince string
+
+	maxBodySize int
+	maxFileSize int64
+
+	resolvedURL *string
+
+	hash hash.Hash
+}
+
+// newDownloadOptions applies opts over a set of default download options.
+func newDownloadOptions(opts []DownloadOption) downloadOptions {
+	var o downloadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// IfNoneMatch makes a download conditional on the contents of a URL no longer matching etag, as reported by its ETag
+// header. The download fails with ErrNotModified should the contents still match.
+func IfNoneMatch(etag string) DownloadOption {
+	return func(o *downloadOptions) {
+		o.ifNoneMatch = etag
+	}
+}
+
+// IfModifiedSince makes a download conditional on the contents of a URL having been modified since lastModified, as
+// reported by its Last-Modified header. The download fails with ErrNotModified should the contents not have been
+// modified since.
+func IfModifiedSince(lastModified string) DownloadOption {
+	return func(o *downloadOptions) {
+		o.ifModifiedSince = lastModified
+	}
+}
+
+// MaxBodySize has a bytes download fail with ErrBodyTooLarge should the contents of a URL exceed n bytes. Contents
+// that are reported to exceed n bytes up front are not downloaded at all. Note that a serially-downloaded response
+// body is read into memory by the underlying transport before it is checked; set MaxResponseBodySize on the
+// *fasthttp.Client used as the Transport to bound it as well.
+func MaxBodySize(n int) DownloadOption {
+	return func(o *downloadOptions) {
+		o.maxBodySize = n
+	}
+}
+
+// MaxFileSize has a file download fail with ErrFileTooLarge should the contents of a URL exceed n bytes. Contents
+// that are reported to exceed n bytes up front are not downloaded at all, and no file is created for them. Contents
+// whose length is not reported up front, or whose server reports a length smaller than the contents it serves, fail
+// as soon as a write past n bytes into the file is attempted. Zero means unlimited.
+func MaxFileSize(n int64) DownloadOption {
+	return func(o *downloadOptions) {
+		o.maxFileSize = n
+	}
+}
+
+// ResolvedURL has a bytes or file download store the URL its contents were downloaded from into dst, which is the
+// URL that was downloaded from after all redirects were followed. dst is set to the URL that was originally
+// downloaded from should it not redirect.
+func ResolvedURL(dst *string) DownloadOption {
+	return func(o *downloadOptions) {
+		o.resolvedURL = dst
+	}
+}
+
+// resolve stores the URL that url resolved to as reported by info into the destination set by ResolvedURL, if any.
+func (o downloadOptions) resolve(url string, info resourceInfo) {
+	if o.resolvedURL == nil {
+		return
+	}
+
+	*o.resolvedURL = info.target(url)
+}
+
+// conditional returns a copy of c which sets the conditional headers prescribed in o on every request. It returns c
+// as-is should o not prescribe any conditions.
+func (c *Client) conditional(o downloadOptions) *Client {
+	if o.ifNoneMatch == "" && o.ifModifiedSince == "" {
+		return c
+	}
+
+	cc := *c
+	cc.Header = make(map[string]string, len(c.Header)+2)
+
+	for key, value := range c.Header {
+		cc.Header[key] = value
+	}
+
+	if o.ifNoneMatch != "" {
+		cc.Header["If-None-Match"] = o.ifNoneMatch
+	}
+
+	if o.ifModifiedSince != "" {
+		cc.Header["If-Modified-Since"] = o.ifModifiedSince
+	}
+
+	return &cc
+}
@@ -0,0 +1,142 @@
+package nicehttp
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuthHeaderSentOnChunkRequests(t *testing.T) {
+	contents := testContents(64)
+
+	var log requestLog
+
+	srv := newContentServer(t, contents, &log)
+
+	c := newTestClient(8, WithBearerToken("token"))
+
+	if _, err := c.DownloadBytes(nil, srv.URL); err != nil {
+		t.Fatalf("failed to download: %s", err)
+	}
+
+	var ranged int
+	for _, req := range log.all() {
+		if req.Authorization != "Bearer token" {
+			t.Fatalf("%s request for range %q carried Authorization %q", req.Method, req.Range, req.Authorization)
+		}
+		if req.Range != "" {
+			ranged++
+		}
+	}
+
+	if ranged == 0 {
+		t.Fatalf("expected contents to be downloaded in chunks")
+	}
+}
+
+func TestAuthHeaderStrippedAfterCrossHostRedirect(t *testing.T) {
+	contents := testContents(64)
+
+	dir, err := ioutil.TempDir("", "nicehttp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	downloads := map[string]func(c *Client, url string) ([]byte, error){
+		"DownloadBytes": func(c *Client, url string) ([]byte, error) {
+			return c.DownloadBytes(nil, url)
+		},
+		"DownloadFile": func(c *Client, url string) ([]byte, error) {
+			filename := filepath.Join(dir, "file")
+			if err := c.DownloadFile(filename, url); err != nil {
+				return nil, err
+			}
+			return ioutil.ReadFile(filename)
+		},
+		"DownloadInChunks": func(c *Client, url string) ([]byte, error) {
+			buf := NewWriteBuffer(make([]byte, len(contents)))
+			if err := c.DownloadInChunks(buf, url, len(contents)); err != nil {
+				return nil, err
+			}
+			return buf.Bytes(), nil
+		},
+	}
+
+	for name, download := range downloads {
+		t.Run(name, func(t *testing.T) {
+			var logA, logB requestLog
+
+			b := newContentServer(t, contents, &logB)
+			a := newRedirectServer(t, b.URL, &logA)
+
+			c := newTestClient(8, WithBasicAuth("user", "password"))
+
+			buf, err := download(&c, a.URL)
+			if err != nil {
+				t.Fatalf("failed to download: %s", err)
+			}
+			if !bytes.Equal(buf, contents) {
+				t.Fatalf("downloaded contents do not match")
+			}
+
+			for _, req := range logA.all() {
+				if !strings.HasPrefix(req.Authorization, "Basic ") {
+					t.Fatalf("original host received %s request without credentials", req.Method)
+				}
+			}
+
+			reqs := logB.all()
+			if len(reqs) == 0 {
+				t.Fatalf("redirected-to host received no requests")
+			}
+
+			for _, req := range reqs {
+				if req.Authorization != "" {
+					t.Fatalf("redirected-to host received credentials on %s request for range %q", req.Method, req.Range)
+				}
+			}
+		})
+	}
+}
+
+func TestWithTLSConfig(t *testing.T) {
+	contents := testContents(64)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(contents))
+	}))
+	srv.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+
+	// The certificate of the test server is signed by a CA that is not trusted by the system.
+
+	untrusted := newTestClient(8)
+
+	if _, err := untrusted.DownloadBytes(nil, srv.URL); err == nil {
+		t.Fatalf("expected download from a server with an untrusted certificate to fail")
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+
+	c := newTestClient(8, WithTLSConfig(&tls.Config{RootCAs: roots}))
+
+	buf, err := c.DownloadBytes(nil, srv.URL)
+	if err != nil {
+		t.Fatalf("failed to download: %s", err)
+	}
+	if !bytes.Equal(buf, contents) {
+		t.Fatalf("downloaded contents do not match")
+	}
+}
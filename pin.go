@@ -0,0 +1,257 @@
+package nicehttp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var _ Transport = (*pinnedTransport)(nil)
+
+// pinnedTransport routes requests made to a single host to a dedicated fasthttp.HostClient that always dials the same
+// IP address. Requests made to any other host (i.e. after a cross-host redirect) are routed to the wrapped Transport.
+type pinnedTransport struct {
+	Transport
+
+	host   []byte
+	pinned *fasthttp.HostClient
+}
+
+func (t *pinnedTransport) Do(req *fasthttp.Request, res *fasthttp.Response) error {
+	if bytes.Equal(req.URI().Host(), t.host) {
+		return t.pinned.Do(req, res)
+	}
+	return t.Transport.Do(req, res)
+}
+
+func (t *pinnedTransport) DoTimeout(req *fasthttp.Request, res *fasthttp.Response, timeout time.Duration) error {
+	if bytes.Equal(req.URI().Host(), t.host) {
+		return t.pinned.DoTimeout(req, res, timeout)
+	}
+	return t.Transport.DoTimeout(req, res, timeout)
+}
+
+func (t *pinnedTransport) DoDeadline(req *fasthttp.Request, res *fasthttp.Response, deadline time.Time) error {
+	if bytes.Equal(req.URI().Host(), t.host) {
+		return t.pinned.DoDeadline(req, res, deadline)
+	}
+	return t.Transport.DoDeadline(req, res, deadline)
+}
+
+// pinToResolvedIP returns a Transport which dials a single resolved IP address for every request made to the hosts
+// of urls, and routes all other requests to c.Instance. The hosts of urls are each resolved once. It returns
+// c.Instance as-is should c.Instance not be a *fasthttp.Client.
+func (c *Client) pinToResolvedIP(urls []string, deadline time.Time) (Transport, error) {
+	base, ok := c.Instance.(*fasthttp.Client)
+	if !ok {
+		return c.Instance, nil
+	}
+
+	instance := c.Instance
+
+	for _, url := range urls {
+		pinned, err := c.pinHost(base, instance, url, deadline)
+		if err != nil {
+			return nil, err
+		}
+
+		instance = pinned
+	}
+
+	return instance, nil
+}
+
+// pinHost resolves the host of url once, and returns a Transport which has a copy of base dial one of the resolved
+// IP addresses for every request made to said host. All other requests are routed to next.
+func (c *Client) pinHost(base *fasthttp.Client, next Transport, url string, deadline time.Time) (Transport, error) {
+	uri := fasthttp.AcquireURI()
+	defer fasthttp.ReleaseURI(uri)
+
+	uri.Update(url)
+
+	isTLS := bytes.Equal(uri.Scheme(), []byte("https"))
+
+	addr := string(uri.Host())
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		if isTLS {
+			addr = net.JoinHostPort(addr, "443")
+		} else {
+			addr = net.JoinHostPort(addr, "80")
+		}
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse address %q: %w", addr, err)
+	}
+
+	var resolved []string
+
+	if ip := net.ParseIP(host); ip != nil {
+		resolved = []string{net.JoinHostPort(ip.String(), port)}
+	} else {
+		ctx := context.Background()
+
+		if !deadline.IsZero() {
+			var cancel context.CancelFunc
+
+			ctx, cancel = context.WithDeadline(ctx, deadline)
+			defer cancel()
+		}
+
+		ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %q: %w", host, err)
+		}
+
+		if len(ips) == 0 {
+			return nil, fmt.Errorf("no ip addresses found for %q", host)
+		}
+
+		for _, ip := range ips {
+			resolved = append(resolved, net.JoinHostPort(ip.IP.String(), port))
+		}
+	}
+
+	key := pinnedHostKey{base: base, addr: addr, isTLS: isTLS}
+
+	var pinned *fasthttp.HostClient
+
+	if c.state != nil {
+		pinned = c.state.pinned.get(key, resolved)
+	} else {
+		pinned = newPinnedHostClient(base, key, resolved)
+	}
+
+	return &pinnedTransport{Transport: next, host: append([]byte(nil), uri.Host()...), pinned: pinned}, nil
+}
+
+// pinnedHostKey identifies the host a fasthttp.HostClient sends requests to on behalf of a fasthttp.Client.
+type pinnedHostKey struct {
+	base  *fasthttp.Client
+	addr  string
+	isTLS bool
+}
+
+// pinnedHost is a fasthttp.HostClient which dials one of a set of resolved addresses.
+type pinnedHost struct {
+	resolved []string
+	client   *fasthttp.HostClient
+}
+
+// pinnedHosts caches the fasthttp.HostClients that requests to pinned hosts are sent through, such that connections
+// to a pinned host are kept alive across downloads rather than being dialed again for every download.
+type pinnedHosts struct {
+	mu    sync.Mutex
+	hosts map[pinnedHostKey]pinnedHost
+}
+
+// get returns the fasthttp.HostClient cached for key, should it dial the same set of addresses as resolved. Otherwise, it
+// caches and returns a new fasthttp.HostClient which dials resolved in place of the one cached before.
+func (p *pinnedHosts) get(key pinnedHostKey, resolved []string) *fasthttp.HostClient {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if h, ok := p.hosts[key]; ok && sameAddrs(h.resolved, resolved) {
+		return h.client
+	}
+
+	if p.hosts == nil {
+		p.hosts = make(map[pinnedHostKey]pinnedHost)
+	}
+
+	client := newPinnedHostClient(key.base, key, resolved)
+	p.hosts[key] = pinnedHost{resolved: resolved, client: client}
+
+	return client
+}
+
+// newPinnedHostClient returns a fasthttp.HostClient which sends requests to the host described by key the same way
+// base would, but which dials one of the addresses in resolved rather than resolving the host itself.
+func newPinnedHostClient(base *fasthttp.Client, key pinnedHostKey, resolved []string) *fasthttp.HostClient {
+	dial := base.Dial
+	if dial == nil {
+		if base.DialDualStack {
+			dial = fasthttp.DialDualStack
+		} else {
+			dial = fasthttp.Dial
+		}
+	}
+
+	d := &pinnedDialer{dial: dial, addrs: resolved}
+
+	return &fasthttp.HostClient{
+		Addr:                          key.addr,
+		Name:                          base.Name,
+		NoDefaultUserAgentHeader:      base.NoDefaultUserAgentHeader,
+		Dial:                          d.Dial,
+		DialDualStack:                 base.DialDualStack,
+		IsTLS:                         key.isTLS,
+		TLSConfig:                     base.TLSConfig,
+		MaxConns:                      base.MaxConnsPerHost,
+		MaxIdleConnDuration:           base.MaxIdleConnDuration,
+		MaxConnDuration:               base.MaxConnDuration,
+		MaxIdemponentCallAttempts:     base.MaxIdemponentCallAttempts,
+		ReadBufferSize:                base.ReadBufferSize,
+		WriteBufferSize:               base.WriteBufferSize,
+		ReadTimeout:                   base.ReadTimeout,
+		WriteTimeout:                  base.WriteTimeout,
+		MaxResponseBodySize:           base.MaxResponseBodySize,
+		DisableHeaderNamesNormalizing: base.DisableHeaderNamesNormalizing,
+		DisablePathNormalizing:        base.DisablePathNormalizing,
+		MaxConnWaitTimeout:            base.MaxConnWaitTimeout,
+	}
+}
+
+// pinnedDialer dials one of a set of resolved addresses. All connections are dialed to the address that was last
+// dialed successfully, moving on to the next address only should dialing said address fail.
+type pinnedDialer struct {
+	dial  fasthttp.DialFunc
+	addrs []string
+	last  uint32
+}
+
+// Dial implements fasthttp.DialFunc, ignoring the address it is asked to dial.
+func (d *pinnedDialer) Dial(string) (net.Conn, error) {
+	start := atomic.LoadUint32(&d.last)
+
+	var err error
+
+	for i := 0; i < len(d.addrs); i++ {
+		k := (start + uint32(i)) % uint32(len(d.addrs))
+
+		var conn net.Conn
+		if conn, err = d.dial(d.addrs[k]); err == nil {
+			atomic.StoreUint32(&d.last, k)
+			return conn, nil
+		}
+	}
+
+	return nil, err
+}
+
+// sameAddrs returns whether or not a and b hold the same addresses, regardless of their order.
+func sameAddrs(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	a, b = append([]string(nil), a...), append([]string(nil), b...)
+
+	sort.Strings(a)
+	sort.Strings(b)
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
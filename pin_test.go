@@ -0,0 +1,166 @@
+package nicehttp
+
+import (
+	"bytes"
+	"errors"
+	"github.com/valyala/fasthttp"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPinToResolvedIP(t *testing.T) {
+	contents := testContents(64)
+
+	// Have every response close its connection, such that every chunk request is sent over a newly-dialed connection.
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Connection", "close")
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(contents))
+	}))
+	defer srv.Close()
+
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to parse address of test server: %s", err)
+	}
+
+	var (
+		mu     sync.Mutex
+		dialed []string
+	)
+
+	// Record the address of every connection dialed, while always connecting to the test server regardless.
+
+	dial := func(addr string) (net.Conn, error) {
+		mu.Lock()
+		dialed = append(dialed, addr)
+		mu.Unlock()
+
+		return net.Dial("tcp", srv.Listener.Addr().String())
+	}
+
+	c := newTestClient(8, WithDial(dial))
+	c.PinToResolvedIP = true
+
+	buf := NewWriteBuffer(make([]byte, len(contents)))
+	if err := c.DownloadInChunks(buf, "http://localhost:"+port, len(contents)); err != nil {
+		t.Fatalf("failed to download: %s", err)
+	}
+	if !bytes.Equal(buf.Bytes(), contents) {
+		t.Fatalf("downloaded contents do not match")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(dialed) < len(contents)/8 {
+		t.Fatalf("expected at least %d connections to be dialed, got %d", len(contents)/8, len(dialed))
+	}
+
+	for _, addr := range dialed {
+		if addr != dialed[0] {
+			t.Fatalf("expected all workers to dial %q, got %q", dialed[0], addr)
+		}
+	}
+
+	host, _, err := net.SplitHostPort(dialed[0])
+	if err != nil || net.ParseIP(host) == nil || strings.Contains(dialed[0], "localhost") {
+		t.Fatalf("expected workers to dial a resolved ip address, got %q", dialed[0])
+	}
+}
+
+func TestPinToResolvedIPReusesConnections(t *testing.T) {
+	contents := testContents(64)
+
+	var log requestLog
+
+	srv := newContentServer(t, contents, &log)
+
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to parse address of test server: %s", err)
+	}
+
+	var dialed int32
+
+	dial := func(addr string) (net.Conn, error) {
+		atomic.AddInt32(&dialed, 1)
+		return net.Dial("tcp", srv.Listener.Addr().String())
+	}
+
+	// Settings of the fasthttp.Client are carried over onto requests sent to the pinned IP address.
+
+	c := WrapClient(&fasthttp.Client{Dial: dial, NoDefaultUserAgentHeader: true})
+	c.NumWorkers = 1
+	c.ChunkSize = 8
+	c.PinToResolvedIP = true
+
+	for i := 0; i < 2; i++ {
+		buf := NewWriteBuffer(make([]byte, len(contents)))
+		if err := c.DownloadInChunks(buf, "http://localhost:"+port, len(contents)); err != nil {
+			t.Fatalf("failed to download: %s", err)
+		}
+		if !bytes.Equal(buf.Bytes(), contents) {
+			t.Fatalf("downloaded contents do not match")
+		}
+	}
+
+	if n := atomic.LoadInt32(&dialed); n != 1 {
+		t.Fatalf("expected a single connection to be kept alive across downloads, got %d connections", n)
+	}
+
+	for _, req := range log.all() {
+		if ua := req.Header.Get("User-Agent"); ua != "" {
+			t.Fatalf("expected no User-Agent to be sent, got %q", ua)
+		}
+	}
+}
+
+func TestPinnedDialerFallsBack(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		dialed []string
+	)
+
+	d := &pinnedDialer{addrs: []string{"10.0.0.1:80", "10.0.0.2:80", "10.0.0.3:80"}}
+
+	// Refuse connections to the first address.
+
+	d.dial = func(addr string) (net.Conn, error) {
+		mu.Lock()
+		dialed = append(dialed, addr)
+		mu.Unlock()
+
+		if addr == "10.0.0.1:80" {
+			return nil, errors.New("connection refused")
+		}
+
+		conn, _ := net.Pipe()
+		return conn, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		conn, err := d.Dial("example.com:80")
+		if err != nil {
+			t.Fatalf("failed to dial: %s", err)
+		}
+		conn.Close()
+	}
+
+	expected := []string{"10.0.0.1:80", "10.0.0.2:80", "10.0.0.2:80", "10.0.0.2:80"}
+	if strings.Join(dialed, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected %v to be dialed, got %v", expected, dialed)
+	}
+
+	d.dial = func(string) (net.Conn, error) { return nil, errors.New("connection refused") }
+
+	if _, err := d.Dial("example.com:80"); err == nil {
+		t.Fatalf("expected dialing to fail once every address refuses connections")
+	}
+}
@@ -0,0 +1,129 @@
+package nicehttp
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DownloadPlan describes how the contents of a URL would be downloaded, as learned from its headers without
+// downloading any of its contents.
+type DownloadPlan struct {
+	// The URL the contents would be downloaded from, after all redirects were followed.
+	URL string
+
+	// Length of the contents in bytes. Zero means the length is not known up front.
+	ContentLength int
+
+	// Decide whether or not the contents would be downloaded in parallel chunks, rather than serially.
+	Chunked bool
+
+	// Size of each chunk the contents would be downloaded in. Zero should the contents not be chunked.
+	ChunkSize int
+
+	// Number of byte ranges that would be requested. One should the contents not be chunked.
+	NumRanges int
+}
+
+// BatchPlan describes how the contents of a batch of URLs would be downloaded.
+type BatchPlan struct {
+	// Plans of each URL, in the same order as the URLs planned. A plan is zero should probing its URL have failed.
+	Plans []DownloadPlan
+
+	// Errors encountered probing each URL, in the same order as the URLs planned. An error is nil should probing its
+	// URL have succeeded.
+	Errs []error
+
+	// Total length in bytes of the contents of all URLs whose length is known up front.
+	ContentLength int64
+
+	// Number of URLs that were probed successfully, but whose length is not known up front.
+	UnknownLengths int
+}
+
+// Plan queries the headers of url, and returns how its contents would be downloaded without downloading any of them.
+// Unlike downloads, Plan fails should url respond with an error status code, such that it may be used to validate
+// URLs ahead of downloading them.
+func (c *Client) Plan(url string) (DownloadPlan, error) {
+	return c.PlanDeadline(url, zeroTime)
+}
+
+// PlanTimeout queries the headers of url, and returns how its contents would be downloaded without downloading any
+// of them.
+func (c *Client) PlanTimeout(url string, timeout time.Duration) (DownloadPlan, error) {
+	return c.PlanDeadline(url, c.clock().Add(timeout))
+}
+
+// PlanDeadline queries the headers of url, and returns how its contents would be downloaded without downloading any
+// of them.
+func (c *Client) PlanDeadline(url string, deadline time.Time) (DownloadPlan, error) {
+	info, err := c.queryInfo(url, deadline, nil)
+	if err != nil {
+		return DownloadPlan{}, fmt.Errorf("failed to plan download of %q: %w", url, err)
+	}
+
+	plan := DownloadPlan{URL: info.target(url), ContentLength: info.contentLength, NumRanges: 1}
+
+	if c.AcceptsRanges && info.acceptsRanges && info.contentLength > 0 {
+		plan.Chunked = true
+		plan.ChunkSize = c.chunkSize(info.contentLength)
+		plan.NumRanges = len(c.scheduler().Schedule(info.contentLength, plan.ChunkSize))
+	}
+
+	return plan, nil
+}
+
+// PlanAll plans the download of the contents of each of urls, probing at most c.MaxConcurrentDownloads URLs at once,
+// and returns their plans alongside the total length of their contents.
+func (c *Client) PlanAll(urls []string) BatchPlan {
+	return c.PlanAllDeadline(urls, zeroTime)
+}
+
+// PlanAllTimeout plans the download of the contents of each of urls, probing at most c.MaxConcurrentDownloads URLs at
+// once.
+func (c *Client) PlanAllTimeout(urls []string, timeout time.Duration) BatchPlan {
+	return c.PlanAllDeadline(urls, c.clock().Add(timeout))
+}
+
+// PlanAllDeadline plans the download of the contents of each of urls, probing at most c.MaxConcurrentDownloads URLs
+// at once.
+func (c *Client) PlanAllDeadline(urls []string, deadline time.Time) BatchPlan {
+	batch := BatchPlan{Plans: make([]DownloadPlan, len(urls)), Errs: make([]error, len(urls))}
+
+	n := c.MaxConcurrentDownloads
+	if n <= 0 {
+		n = 1
+	}
+
+	sem := make(chan struct{}, n)
+
+	var wg sync.WaitGroup
+	wg.Add(len(urls))
+
+	for i := range urls {
+		i := i
+
+		sem <- struct{}{}
+
+		go func() {
+			defer func() { <-sem }()
+			defer wg.Done()
+
+			batch.Plans[i], batch.Errs[i] = c.PlanDeadline(urls[i], deadline)
+		}()
+	}
+
+	wg.Wait()
+
+	for i, plan := range batch.Plans {
+		switch {
+		case batch.Errs[i] != nil:
+		case plan.ContentLength > 0:
+			batch.ContentLength += int64(plan.ContentLength)
+		default:
+			batch.UnknownLengths++
+		}
+	}
+
+	return batch
+}
@@ -0,0 +1,114 @@
+package nicehttp
+
+import (
+	"context"
+	"errors"
+	"github.com/valyala/fasthttp"
+	"sync"
+)
+
+// ErrPoolClosed is returned should a chunk be dispatched to a WorkerPool that has been closed.
+var ErrPoolClosed = errors.New("worker pool is closed")
+
+// WorkerPool is a long-lived pool of goroutines which download chunks on behalf of any number of concurrent downloads
+// made by Clients whose Pool is set to it. Each goroutine holds onto a single request and response for its entire
+// lifetime, rather than acquiring a fresh request and response for every worker of every download.
+//
+// The size of the pool bounds the number of chunks downloaded at once across all downloads sharing it. Each download
+// still hands out its byte ranges to Client.NumWorkers workers, which are queued up onto the pool.
+//
+// A pool does not make downloads any cheaper, as fasthttp already pools requests and responses.
+// BenchmarkDownloadInChunks downloads 64 KiB in 4 KiB chunks using 8 workers from a stub Transport, and on a single
+// core measures ~42µs, 9138 B and 296 allocs per download without a pool, against ~42µs, 10273 B and 313 allocs per
+// download with a pool of 8 goroutines, the difference being the channel and closure that hand each worker over to the
+// pool. A pool should instead be used to cap the number of chunks downloaded at once across concurrent downloads.
+type WorkerPool struct {
+	tasks chan poolTask
+	wg    sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// poolTask is a worker of a download that is to be run by a goroutine of a WorkerPool, alongside a channel that the
+// error it returns is to be sent to.
+type poolTask struct {
+	work func(req *fasthttp.Request, res *fasthttp.Response) error
+	done chan<- error
+}
+
+// NewWorkerPool spawns a pool of size goroutines that download chunks. Close must be called to stop them.
+func NewWorkerPool(size int) *WorkerPool {
+	if size < 1 {
+		size = 1
+	}
+
+	p := &WorkerPool{tasks: make(chan poolTask)}
+
+	p.wg.Add(size)
+
+	for i := 0; i < size; i++ {
+		go p.loop()
+	}
+
+	return p
+}
+
+func (p *WorkerPool) loop() {
+	defer p.wg.Done()
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	res := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(res)
+
+	for t := range p.tasks {
+		t.done <- t.work(req, res)
+
+		req.Reset()
+		res.Reset()
+	}
+}
+
+// run queues work up onto the pool, and waits for it to complete. It returns early should ctx be canceled before
+// work is picked up by a goroutine of the pool.
+func (p *WorkerPool) run(ctx context.Context, work func(req *fasthttp.Request, res *fasthttp.Response) error) error {
+	done := make(chan error, 1)
+
+	p.mu.RLock()
+
+	if p.closed {
+		p.mu.RUnlock()
+		return ErrPoolClosed
+	}
+
+	select {
+	case p.tasks <- poolTask{work: work, done: done}:
+	case <-ctx.Done():
+		p.mu.RUnlock()
+		return contextError(ctx)
+	}
+
+	p.mu.RUnlock()
+
+	return <-done
+}
+
+// Close stops all goroutines of the pool once they have finished downloading the chunks they are working on.
+// Downloads that dispatch chunks to the pool after it has been closed fail with ErrPoolClosed.
+func (p *WorkerPool) Close() {
+	p.mu.Lock()
+
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+
+	p.closed = true
+	close(p.tasks)
+
+	p.mu.Unlock()
+
+	p.wg.Wait()
+}
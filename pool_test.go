@@ -0,0 +1,127 @@
+package nicehttp
+
+import (
+	"bytes"
+	"errors"
+	"github.com/valyala/fasthttp"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWorkerPoolSharedAcrossDownloads(t *testing.T) {
+	contents := testContents(256)
+
+	var inflight, peak int32
+
+	serve := rangeTransport(contents)
+
+	pool := NewWorkerPool(2)
+	defer pool.Close()
+
+	c := WrapClient(transportFunc(func(req *fasthttp.Request, res *fasthttp.Response) error {
+		n := atomic.AddInt32(&inflight, 1)
+		defer atomic.AddInt32(&inflight, -1)
+
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+
+		return serve(req, res)
+	}))
+	c.NumWorkers = 8
+	c.ChunkSize = 8
+	c.Pool = pool
+
+	// Dispatch chunks of several downloads at once onto a pool smaller than the number of workers of each download.
+
+	var wg sync.WaitGroup
+
+	errs := make([]error, 4)
+	bufs := make([]*WriteBuffer, len(errs))
+
+	for i := range errs {
+		i := i
+
+		bufs[i] = NewWriteBuffer(make([]byte, len(contents)))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = c.DownloadInChunks(bufs[i], "http://example.com/file", len(contents))
+		}()
+	}
+
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("failed to download: %s", err)
+		}
+		if !bytes.Equal(bufs[i].Bytes(), contents) {
+			t.Fatalf("downloaded contents do not match")
+		}
+	}
+
+	if p := atomic.LoadInt32(&peak); p > 2 {
+		t.Fatalf("expected at most 2 chunks to be downloaded at once by a pool of size 2, got %d", p)
+	}
+}
+
+func TestWorkerPoolClose(t *testing.T) {
+	contents := testContents(64)
+
+	pool := NewWorkerPool(2)
+
+	c := WrapClient(rangeTransport(contents))
+	c.NumWorkers = 2
+	c.ChunkSize = 8
+	c.Pool = pool
+
+	pool.Close()
+	pool.Close()
+
+	buf := NewWriteBuffer(make([]byte, len(contents)))
+
+	if err := c.DownloadInChunks(buf, "http://example.com/file", len(contents)); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("expected ErrPoolClosed, got %v", err)
+	}
+}
+
+func BenchmarkDownloadInChunks(b *testing.B) {
+	contents := testContents(64 * 1024)
+
+	c := WrapClient(rangeTransport(contents))
+	c.NumWorkers = 8
+	c.ChunkSize = 4 * 1024
+
+	dst := make([]byte, len(contents))
+
+	run := func(b *testing.B, c Client) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(contents)))
+
+		for i := 0; i < b.N; i++ {
+			if err := c.DownloadInChunks(NewWriteBuffer(dst), "http://example.com/file", len(contents)); err != nil {
+				b.Fatalf("failed to download: %s", err)
+			}
+		}
+	}
+
+	b.Run("NoPool", func(b *testing.B) {
+		run(b, c)
+	})
+
+	b.Run("Pool", func(b *testing.B) {
+		pool := NewWorkerPool(c.NumWorkers)
+		defer pool.Close()
+
+		cc := c
+		cc.Pool = pool
+
+		run(b, cc)
+	})
+}
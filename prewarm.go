@@ -0,0 +1,51 @@
+package nicehttp
+
+import (
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/sync/errgroup"
+	"time"
+)
+
+// Prewarm opens n connections to the host of url ahead of a download by sending n HEAD requests to url at once, such
+// that the first chunks of a download that follows do not each pay for dialing and for a TLS handshake before they
+// are requested. n is typically set to NumWorkers.
+//
+// It only helps should the Transport of the Client be a *fasthttp.Client or any other Transport that pools its
+// connections, such that the connections opened are kept alive and reused by the download. Connections are only kept
+// alive for as long as the MaxIdleConnDuration of the *fasthttp.Client, and only reused by downloads to the same host.
+func (c *Client) Prewarm(url string, n int) error {
+	return c.PrewarmDeadline(url, n, zeroTime)
+}
+
+// PrewarmTimeout opens n connections to the host of url ahead of a download by sending n HEAD requests to url at once.
+func (c *Client) PrewarmTimeout(url string, n int, timeout time.Duration) error {
+	return c.PrewarmDeadline(url, n, c.clock().Add(timeout))
+}
+
+// PrewarmDeadline opens n connections to the host of url ahead of a download by sending n HEAD requests to url at
+// once.
+func (c *Client) PrewarmDeadline(url string, n int, deadline time.Time) error {
+	var g errgroup.Group
+
+	for i := 0; i < n; i++ {
+		g.Go(func() error {
+			req := fasthttp.AcquireRequest()
+			defer fasthttp.ReleaseRequest(req)
+
+			res := fasthttp.AcquireResponse()
+			defer fasthttp.ReleaseResponse(res)
+
+			req.Header.SetMethod(fasthttp.MethodHead)
+			req.SetRequestURI(url)
+
+			return c.DoDeadline(req, res, deadline)
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return fmt.Errorf("failed to prewarm connections to %q: %w", url, err)
+	}
+
+	return nil
+}
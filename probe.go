@@ -0,0 +1,66 @@
+package nicehttp
+
+import (
+	"errors"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"time"
+)
+
+// ErrRangesNotSupported is returned by ProbeContentLength should a server not respond to a request for a byte range
+// with said byte range.
+var ErrRangesNotSupported = errors.New("server does not support byte ranges")
+
+// ErrProbeIncomplete is returned should the headers of a URL be queried using a Client.ProbeMethod other than HEAD,
+// and the response yield neither the length of the contents of the URL nor whether they may be requested in byte
+// ranges.
+var ErrProbeIncomplete = errors.New("response yielded neither a content length nor accept ranges")
+
+// ProbeContentLength learns the content length of url by requesting its first byte, and reading the total length of
+// its contents from the "/N" suffix of the Content-Range header of the response. It is useful for servers that serve
+// byte ranges, but that do not report a Content-Length in response to a HEAD request.
+func (c *Client) ProbeContentLength(url string) (int, error) {
+	return c.ProbeContentLengthDeadline(url, zeroTime)
+}
+
+// ProbeContentLengthTimeout learns the content length of url by requesting its first byte, and reading the total
+// length of its contents from the Content-Range header of the response.
+func (c *Client) ProbeContentLengthTimeout(url string, timeout time.Duration) (int, error) {
+	return c.ProbeContentLengthDeadline(url, c.clock().Add(timeout))
+}
+
+// ProbeContentLengthDeadline learns the content length of url by requesting its first byte, and reading the total
+// length of its contents from the Content-Range header of the response.
+func (c *Client) ProbeContentLengthDeadline(url string, deadline time.Time) (int, error) {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	res := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(res)
+
+	req.SetRequestURI(url)
+	req.Header.SetByteRange(0, 0)
+
+	if err := c.DoDeadline(req, res, deadline); err != nil {
+		return 0, fmt.Errorf("failed to probe %q: %w", url, err)
+	}
+
+	if err := c.checkStatus(res); err != nil {
+		return 0, fmt.Errorf("failed to probe %q: %w", url, err)
+	}
+
+	if res.StatusCode() != fasthttp.StatusPartialContent {
+		return 0, fmt.Errorf("failed to probe %q: got status code %d: %w", url, res.StatusCode(), ErrRangesNotSupported)
+	}
+
+	_, _, total, ok := parseContentRange(res.Header.Peek("Content-Range"))
+	if !ok {
+		return 0, fmt.Errorf("failed to probe %q: malformed Content-Range %q", url, res.Header.Peek("Content-Range"))
+	}
+
+	if total < 0 {
+		return 0, fmt.Errorf("failed to probe %q: server did not report the total length of its contents", url)
+	}
+
+	return total, nil
+}
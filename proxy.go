@@ -0,0 +1,135 @@
+package nicehttp
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// proxyHandshakeTimeout is the max amount of time that dialing a proxy, and tunneling a connection through it using
+// the CONNECT method, may take.
+const proxyHandshakeTimeout = 10 * time.Second
+
+// WithDial has all connections made by the Client be dialed using dial. It applies to every request made by the
+// Client, including requests for headers and requests for chunks.
+//
+// It only has an effect should the Transport of the Client be a *fasthttp.Client, whose Dial func is then replaced
+// by dial. Any other Transport is expected to have its own dialer configured instead. Note that the Transport set by
+// WithTransport is modified in place, and that options are applied in order, such that WithDial must be provided
+// after WithTransport.
+func WithDial(dial fasthttp.DialFunc) Option {
+	return func(c *Client) {
+		if instance, ok := c.Instance.(*fasthttp.Client); ok {
+			instance.Dial = dial
+		}
+	}
+}
+
+// WithProxy has all requests made by the Client be tunneled through the HTTP proxy at proxyURL using the CONNECT
+// method. proxyURL is of the form "http://[user:password@]host:port", or "[user:password@]host:port". Credentials
+// in proxyURL are sent to the proxy using Basic authentication.
+//
+// As fasthttp dials connections regardless of the deadline of a request, dialing the proxy and tunneling a connection
+// through it fails should it take longer than 10 seconds.
+//
+// Like WithDial, it only has an effect should the Transport of the Client be a *fasthttp.Client.
+func WithProxy(proxyURL string) Option {
+	return WithDial(httpProxyDialer(proxyURL, proxyHandshakeTimeout))
+}
+
+// httpProxyDialer returns a fasthttp.DialFunc which tunnels connections through the HTTP proxy at proxyURL. Dialing
+// the proxy and tunneling a connection through it fails should it take longer than timeout.
+func httpProxyDialer(proxyURL string, timeout time.Duration) fasthttp.DialFunc {
+	if !strings.Contains(proxyURL, "://") {
+		proxyURL = "http://" + proxyURL
+	}
+
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return func(string) (net.Conn, error) {
+			return nil, fmt.Errorf("failed to parse proxy url: %w", err)
+		}
+	}
+
+	var auth string
+	if u.User != nil {
+		password, _ := u.User.Password()
+		auth = base64.StdEncoding.EncodeToString([]byte(u.User.Username() + ":" + password))
+	}
+
+	return func(addr string) (net.Conn, error) {
+		deadline := time.Now().Add(timeout)
+
+		conn, err := fasthttp.DialTimeout(u.Host, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to dial proxy %q: %w", u.Host, err)
+		}
+
+		if err := conn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to set deadline on connection to proxy %q: %w", u.Host, err)
+		}
+
+		req := "CONNECT " + addr + " HTTP/1.1\r\nHost: " + addr + "\r\n"
+		if auth != "" {
+			req += "Proxy-Authorization: Basic " + auth + "\r\n"
+		}
+		req += "\r\n"
+
+		if _, err := conn.Write([]byte(req)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to write CONNECT request to proxy %q: %w", u.Host, err)
+		}
+
+		res := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseResponse(res)
+
+		res.SkipBody = true
+
+		br := bufio.NewReader(conn)
+
+		if err := res.Read(br); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to read CONNECT response from proxy %q: %w", u.Host, err)
+		}
+
+		if res.StatusCode() != fasthttp.StatusOK {
+			conn.Close()
+			return nil, fmt.Errorf("proxy %q refused to connect to %q: got status code %d", u.Host, addr, res.StatusCode())
+		}
+
+		if err := conn.SetDeadline(zeroTime); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to clear deadline on connection to proxy %q: %w", u.Host, err)
+		}
+
+		// The proxy may have already relayed bytes from addr past its response, which were read into br.
+
+		if br.Buffered() > 0 {
+			return &bufferedConn{Conn: conn, r: br}, nil
+		}
+
+		return conn, nil
+	}
+}
+
+// bufferedConn is a net.Conn whose reads are served from r until r has no more bytes buffered.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	if c.r != nil {
+		if c.r.Buffered() > 0 {
+			return c.r.Read(b)
+		}
+		c.r = nil
+	}
+	return c.Conn.Read(b)
+}
@@ -0,0 +1,180 @@
+package nicehttp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newConnectProxy starts a test HTTP proxy which tunnels connections using the CONNECT method, and which requires
+// the credentials user:password to be provided should user not be empty. It returns the proxy, and a counter of the
+// number of connections it tunneled.
+func newConnectProxy(t *testing.T, user, password string) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var tunneled int32
+
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		if user != "" && r.Header.Get("Proxy-Authorization") != auth {
+			w.Header().Set("Proxy-Authenticate", `Basic realm="proxy"`)
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+
+		dst, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer dst.Close()
+
+		src, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer src.Close()
+
+		atomic.AddInt32(&tunneled, 1)
+
+		if _, err := io.WriteString(src, "HTTP/1.1 200 Connection established\r\n\r\n"); err != nil {
+			return
+		}
+
+		go io.Copy(dst, src)
+		io.Copy(src, dst)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &tunneled
+}
+
+func TestWithProxy(t *testing.T) {
+	contents := testContents(64)
+
+	srv := newContentServer(t, contents, nil)
+	proxy, tunneled := newConnectProxy(t, "user", "password")
+
+	addr := strings.TrimPrefix(proxy.URL, "http://")
+
+	c := newTestClient(8, WithProxy("user:password@"+addr))
+
+	dst, err := c.DownloadBytes(nil, srv.URL)
+	if err != nil {
+		t.Fatalf("failed to download through proxy: %s", err)
+	}
+	if !bytes.Equal(dst, contents) {
+		t.Fatalf("downloaded contents do not match")
+	}
+
+	if atomic.LoadInt32(tunneled) == 0 {
+		t.Fatalf("expected connections to be tunneled through the proxy")
+	}
+
+	c = newTestClient(8, WithProxy("http://user:wrong@"+addr))
+
+	_, err = c.DownloadBytes(nil, srv.URL)
+	if err == nil || !strings.Contains(err.Error(), "407") {
+		t.Fatalf("expected the proxy to refuse wrong credentials with a 407, got %v", err)
+	}
+}
+
+// newRawProxy starts a listener which responds to the first request sent over every connection it accepts with
+// response, and which then holds the connection open until the test completes.
+func newRawProxy(t *testing.T, response string) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+
+	done := make(chan struct{})
+
+	t.Cleanup(func() {
+		close(done)
+		ln.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == "\r\n" {
+						break
+					}
+				}
+
+				if response != "" {
+					io.WriteString(conn, response)
+				}
+
+				<-done
+			}()
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+func TestProxyDialerKeepsBytesPastResponse(t *testing.T) {
+	addr := newRawProxy(t, "HTTP/1.1 200 Connection established\r\n\r\nhello")
+
+	conn, err := httpProxyDialer(addr, time.Second)("example.com:80")
+	if err != nil {
+		t.Fatalf("failed to dial through proxy: %s", err)
+	}
+	defer conn.Close()
+
+	if err := conn.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("failed to set read deadline: %s", err)
+	}
+
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("failed to read from tunnel: %s", err)
+	}
+	if string(buf) != "hello" {
+		t.Fatalf("expected bytes sent past the response of the proxy to be read, got %q", buf)
+	}
+}
+
+func TestProxyDialerTimeout(t *testing.T) {
+	addr := newRawProxy(t, "")
+
+	start := time.Now()
+
+	if _, err := httpProxyDialer(addr, 100*time.Millisecond)("example.com:80"); err == nil {
+		t.Fatalf("expected dialing through a stalled proxy to fail")
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("expected dialing through a stalled proxy to give up near its timeout, took %s", elapsed)
+	}
+}
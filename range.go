@@ -0,0 +1,88 @@
+package nicehttp
+
+import (
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"io"
+	"time"
+)
+
+// DownloadRange downloads the half-open byte range [start, end) of the contents of url using a single request, and
+// writes it to w, i.e. to read the central directory at the end of a remote ZIP archive without downloading all of
+// it. It returns the number of bytes written to w.
+//
+// Should end exceed the length of the contents, only the bytes from start up to the end of the contents are written,
+// and their number is returned. It fails with ErrRangeIgnored should the server respond with the entirety of the
+// contents rather than with the byte range requested, and with a *HTTPError should start exceed the length of the
+// contents.
+func (c *Client) DownloadRange(w io.Writer, url string, start, end int) (int, error) {
+	return c.DownloadRangeDeadline(w, url, start, end, zeroTime)
+}
+
+// DownloadRangeTimeout downloads the half-open byte range [start, end) of the contents of url using a single request,
+// and writes it to w. It returns the number of bytes written to w.
+func (c *Client) DownloadRangeTimeout(w io.Writer, url string, start, end int, timeout time.Duration) (int, error) {
+	return c.DownloadRangeDeadline(w, url, start, end, c.clock().Add(timeout))
+}
+
+// DownloadRangeDeadline downloads the half-open byte range [start, end) of the contents of url using a single
+// request, and writes it to w. It returns the number of bytes written to w.
+func (c *Client) DownloadRangeDeadline(w io.Writer, url string, start, end int, deadline time.Time) (int, error) {
+	if start < 0 || end <= start {
+		return 0, fmt.Errorf("invalid byte range (start: %d, end: %d)", start, end)
+	}
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	res := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(res)
+
+	req.SetRequestURI(url)
+	req.Header.SetByteRange(start, end-1)
+
+	if err := c.DoDeadline(req, res, deadline); err != nil {
+		return 0, fmt.Errorf("failed to download bytes %d-%d of %q: %w", start, end-1, url, err)
+	}
+
+	if res.StatusCode() == fasthttp.StatusRequestedRangeNotSatisfiable {
+		return 0, fmt.Errorf("failed to download bytes %d-%d of %q: %w", start, end-1, url, newHTTPError(res))
+	}
+
+	if err := c.checkStatus(res); err != nil {
+		return 0, fmt.Errorf("failed to download bytes %d-%d of %q: %w", start, end-1, url, err)
+	}
+
+	if res.StatusCode() == fasthttp.StatusOK {
+		return 0, fmt.Errorf("failed to download bytes %d-%d of %q: %w", start, end-1, url, ErrRangeIgnored)
+	}
+
+	if res.StatusCode() != fasthttp.StatusPartialContent {
+		return 0, fmt.Errorf("failed to download bytes %d-%d of %q: unexpected status code %d", start, end-1, url, res.StatusCode())
+	}
+
+	if contentEncoding(&res.Header) != "" {
+		return 0, fmt.Errorf("failed to download bytes %d-%d of %q: %w", start, end-1, url, ErrRangeEncoded)
+	}
+
+	// Servers may only respond with fewer bytes than requested should the contents end before end.
+
+	gotStart, gotEnd, _, ok := parseContentRange(res.Header.Peek("Content-Range"))
+	if !ok || gotStart != start || gotEnd > end {
+		return 0, fmt.Errorf("requested bytes %d-%d, got Content-Range %q: %w", start, end-1, res.Header.Peek("Content-Range"), ErrRangeMismatch)
+	}
+
+	if n := len(res.Body()); n != gotEnd-gotStart {
+		return 0, fmt.Errorf("failed to download bytes %d-%d of %q: got %d byte(s), expected %d: %w", start, end-1, url, n, gotEnd-gotStart, ErrShortDownload)
+	}
+
+	if err := res.BodyWriteTo(c.throttleWriter(w, deadline)); err != nil {
+		return 0, fmt.Errorf("failed to write bytes %d-%d of %q: %w", start, end-1, url, err)
+	}
+
+	n := len(res.Body())
+
+	c.wrote(int64(n))
+
+	return n, nil
+}
@@ -0,0 +1,385 @@
+package nicehttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// chunkTracker keeps track of which chunks of a download have already been downloaded.
+type chunkTracker interface {
+	completed(start, end int) bool
+	complete(start, end int) error
+}
+
+var (
+	_ chunkTracker     = (*DownloadState)(nil)
+	_ chunkTracker     = (*resumeState)(nil)
+	_ json.Marshaler   = (*DownloadState)(nil)
+	_ json.Unmarshaler = (*DownloadState)(nil)
+)
+
+// DownloadState is the state of a resumable download, which records which chunks of the contents of a URL have been
+// downloaded. It may be marshaled into JSON at any point throughout the course of a download, persisted, and later
+// unmarshaled and passed to Client.ResumeDownload to only download the chunks that are missing.
+//
+// Completed is a bitmap of which chunks have been downloaded, where the i-th bit of Completed is set should the i-th
+// chunk of ChunkSize bytes have been downloaded.
+type DownloadState struct {
+	URL           string
+	ETag          string
+	LastModified  string
+	ContentLength int
+	ChunkSize     int
+	Completed     []byte
+
+	mu sync.Mutex
+}
+
+// downloadStateJSON is the JSON representation of a DownloadState.
+type downloadStateJSON struct {
+	URL           string `json:"url"`
+	ETag          string `json:"etag,omitempty"`
+	LastModified  string `json:"last_modified,omitempty"`
+	ContentLength int    `json:"content_length"`
+	ChunkSize     int    `json:"chunk_size"`
+	Completed     []byte `json:"completed"`
+}
+
+// NewDownloadState instantiates the state of a download of url which has yet to have any of its chunks downloaded.
+func NewDownloadState(url string) *DownloadState {
+	return &DownloadState{URL: url}
+}
+
+// MarshalJSON implements json.Marshaler. It may be called while the download s is the state of is in progress.
+func (s *DownloadState) MarshalJSON() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return json.Marshal(downloadStateJSON{
+		URL:           s.URL,
+		ETag:          s.ETag,
+		LastModified:  s.LastModified,
+		ContentLength: s.ContentLength,
+		ChunkSize:     s.ChunkSize,
+		Completed:     s.Completed,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *DownloadState) UnmarshalJSON(buf []byte) error {
+	var v downloadStateJSON
+	if err := json.Unmarshal(buf, &v); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.URL = v.URL
+	s.ETag = v.ETag
+	s.LastModified = v.LastModified
+	s.ContentLength = v.ContentLength
+	s.ChunkSize = v.ChunkSize
+	s.Completed = v.Completed
+
+	return nil
+}
+
+// matches reports whether or not s may be resumed from given the url being downloaded, the information queried
+// about it, and the size of the chunks it is to be downloaded in. State lacking both an ETag and a Last-Modified
+// validator never matches, as it may not safely be resumed from.
+func (s *DownloadState) matches(url string, info resourceInfo, chunkSize int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ETag == "" && s.LastModified == "" {
+		return false
+	}
+
+	if s.URL != url || s.ETag != info.etag || s.LastModified != info.lastModified {
+		return false
+	}
+
+	if s.ContentLength != info.contentLength || s.ChunkSize != chunkSize {
+		return false
+	}
+
+	return len(s.Completed) == numChunkBytes(info.contentLength, chunkSize)
+}
+
+// reset resets s into the state of a download of url which has yet to have any of its chunks downloaded.
+func (s *DownloadState) reset(url string, info resourceInfo, chunkSize int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.URL = url
+	s.ETag = info.etag
+	s.LastModified = info.lastModified
+	s.ContentLength = info.contentLength
+	s.ChunkSize = chunkSize
+	s.Completed = make([]byte, numChunkBytes(info.contentLength, chunkSize))
+}
+
+func (s *DownloadState) completed(start, _ int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := start / s.ChunkSize
+	return s.Completed[i/8]&(1<<(i%8)) != 0
+}
+
+func (s *DownloadState) complete(start, _ int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := start / s.ChunkSize
+	s.Completed[i/8] |= 1 << (i % 8)
+
+	return nil
+}
+
+// numChunkBytes returns the number of bytes needed for a bitmap of the chunks of chunkSize bytes that contents
+// comprised of contentLength bytes are split into.
+func numChunkBytes(contentLength, chunkSize int) int {
+	if chunkSize <= 0 {
+		return 0
+	}
+
+	numChunks := (contentLength + chunkSize - 1) / chunkSize
+
+	return (numChunks + 7) / 8
+}
+
+// resumeState is the DownloadState of a resumable download that is persisted into a sidecar metadata file next to
+// the partially-downloaded file every time a chunk has been downloaded.
+type resumeState struct {
+	DownloadState
+
+	saveMu sync.Mutex
+	path   string
+}
+
+// newResumeState instantiates the state of a resumable download of url, which has yet to have any of its chunks
+// downloaded.
+func newResumeState(path, url string, info resourceInfo, chunkSize int) *resumeState {
+	s := &resumeState{path: path}
+	s.reset(url, info, chunkSize)
+
+	return s
+}
+
+// loadResumeState loads the state of a resumable download from path. It returns nil should there not be any state
+// persisted at path, or should the persisted state not match url and the validators in info.
+func loadResumeState(path, url string, info resourceInfo, chunkSize int) *resumeState {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	s := &resumeState{path: path}
+	if err := json.Unmarshal(buf, &s.DownloadState); err != nil {
+		return nil
+	}
+
+	if !s.matches(url, info, chunkSize) {
+		return nil
+	}
+
+	return s
+}
+
+func (s *resumeState) complete(start, end int) error {
+	if err := s.DownloadState.complete(start, end); err != nil {
+		return err
+	}
+
+	return s.save()
+}
+
+// save atomically persists s to its path. State lacking both an ETag and a Last-Modified validator is never
+// persisted, as it may not safely be resumed from.
+func (s *resumeState) save() error {
+	if s.ETag == "" && s.LastModified == "" {
+		return nil
+	}
+
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	buf, err := json.Marshal(&s.DownloadState)
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+
+	if err := ioutil.WriteFile(tmp, buf, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, s.path)
+}
+
+// DownloadFileResumable downloads the contents of url into filename+".part", and renames it to filename once the
+// download has completed. The chunks that have been downloaded are recorded into filename+".part.meta", such that
+// should the download fail, calling DownloadFileResumable again only downloads the chunks that are missing.
+//
+// A download is only resumed should url still report the same ETag and/or Last-Modified header, and the same content
+// length. Should url not accept being downloaded in parallel chunks, or should it report neither an ETag nor a
+// Last-Modified header, the download starts over from scratch.
+func (c *Client) DownloadFileResumable(filename, url string) error {
+	return c.DownloadFileResumableDeadline(filename, url, zeroTime)
+}
+
+// DownloadFileResumableTimeout downloads the contents of url into filename+".part", and renames it to filename once
+// the download has completed. A failed download may be resumed by calling it again.
+func (c *Client) DownloadFileResumableTimeout(filename, url string, timeout time.Duration) error {
+	return c.DownloadFileResumableDeadline(filename, url, c.clock().Add(timeout))
+}
+
+// DownloadFileResumableDeadline downloads the contents of url into filename+".part", and renames it to filename once
+// the download has completed. A failed download may be resumed by calling it again.
+func (c *Client) DownloadFileResumableDeadline(filename, url string, deadline time.Time) (err error) {
+	partPath := filename + ".part"
+	metaPath := partPath + ".meta"
+
+	info, err := c.queryInfo(url, deadline, nil)
+	if err != nil {
+		return fmt.Errorf("failed to query headers of %q: %w", url, err)
+	}
+
+	c = c.forTarget(url, info)
+
+	chunkSize := c.chunkSize(info.contentLength)
+
+	resumable := c.AcceptsRanges && info.acceptsRanges && info.contentLength > 0 && chunkSize > 0
+
+	var state *resumeState
+
+	if resumable {
+		state = loadResumeState(metaPath, url, info, chunkSize)
+	}
+
+	flags := os.O_RDWR | os.O_CREATE
+	if state == nil {
+		flags |= os.O_TRUNC
+	}
+
+	w, err := os.OpenFile(partPath, flags, 0666)
+	if err != nil {
+		return fmt.Errorf("failed to open part file: %w", err)
+	}
+
+	// Close the part file should the download fail. It is otherwise closed explicitly below such that an error
+	// closing it is reported.
+
+	defer func() {
+		if err != nil {
+			w.Close()
+		}
+	}()
+
+	if !resumable {
+		if err := c.DownloadDeadline(w, info.target(url), info.contentLength, info.acceptsRanges, deadline); err != nil {
+			return err
+		}
+	} else {
+		if state == nil {
+			state = newResumeState(metaPath, url, info, chunkSize)
+
+			if err := state.save(); err != nil {
+				return fmt.Errorf("failed to save download state: %w", err)
+			}
+		}
+
+		if err := c.allocate(w, int64(info.contentLength)); err != nil {
+			return fmt.Errorf("failed to allocate %d byte(s) for file: %w", info.contentLength, err)
+		}
+
+		c.event(Started{URL: url, ContentLength: info.contentLength, Chunked: true})
+
+		if err := c.downloadInChunks(w, []string{info.target(url)}, info.contentLength, deadline, state); err != nil {
+			return err
+		}
+	}
+
+	if err := c.sync(w); err != nil {
+		return fmt.Errorf("failed to sync part file: %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to close part file: %w", err)
+	}
+
+	if err := os.Rename(partPath, filename); err != nil {
+		return fmt.Errorf("failed to rename part file: %w", err)
+	}
+
+	if err := os.Remove(metaPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove download state: %w", err)
+	}
+
+	return nil
+}
+
+// ResumeDownload downloads the contents of state.URL into w, skipping over the chunks that state records as having
+// already been downloaded, and records into state every chunk that is downloaded. state may be persisted at any point
+// throughout the course of the download by marshaling it into JSON, such that should the download fail, or should the
+// process exit, the download may be resumed by unmarshaling it and calling ResumeDownload again.
+//
+// w is expected to hold the bytes written to it by the previous attempts at the download, i.e. be a file that is
+// opened without being truncated. The download is only resumed should state.URL still report the same ETag and/or
+// Last-Modified header, content length, and chunk size as the one recorded in state. Otherwise, state is reset, w is
+// truncated should it implement Truncate(size int64) error, and the download starts over from scratch. Should
+// state.URL not accept being downloaded in parallel chunks, it is downloaded serially instead.
+func (c *Client) ResumeDownload(w Writer, state *DownloadState) error {
+	return c.ResumeDownloadDeadline(w, state, zeroTime)
+}
+
+// ResumeDownloadTimeout downloads the contents of state.URL into w, skipping over the chunks that state records as
+// having already been downloaded, and records into state every chunk that is downloaded.
+func (c *Client) ResumeDownloadTimeout(w Writer, state *DownloadState, timeout time.Duration) error {
+	return c.ResumeDownloadDeadline(w, state, c.clock().Add(timeout))
+}
+
+// ResumeDownloadDeadline downloads the contents of state.URL into w, skipping over the chunks that state records as
+// having already been downloaded, and records into state every chunk that is downloaded.
+func (c *Client) ResumeDownloadDeadline(w Writer, state *DownloadState, deadline time.Time) error {
+	state.mu.Lock()
+	url := state.URL
+	state.mu.Unlock()
+
+	info, err := c.queryInfo(url, deadline, nil)
+	if err != nil {
+		return fmt.Errorf("failed to query headers of %q: %w", url, err)
+	}
+
+	c = c.forTarget(url, info)
+
+	chunkSize := c.chunkSize(info.contentLength)
+
+	resumable := c.AcceptsRanges && info.acceptsRanges && info.contentLength > 0 && chunkSize > 0
+
+	if !resumable || !state.matches(url, info, chunkSize) {
+		state.reset(url, info, chunkSize)
+
+		if t, ok := w.(interface{ Truncate(size int64) error }); ok {
+			if err := t.Truncate(0); err != nil {
+				return fmt.Errorf("failed to truncate: %w", err)
+			}
+		}
+	}
+
+	if !resumable {
+		return c.DownloadDeadline(w, info.target(url), info.contentLength, info.acceptsRanges, deadline)
+	}
+
+	c.event(Started{URL: url, ContentLength: info.contentLength, Chunked: true})
+
+	return c.downloadInChunks(w, []string{info.target(url)}, info.contentLength, deadline, state)
+}
@@ -0,0 +1,168 @@
+package nicehttp
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDownloadFileResumable(t *testing.T) {
+	contents := testContents(64)
+
+	var (
+		mu     sync.Mutex
+		etag   = `"v1"`
+		fail   = "bytes=32-39"
+		ranges []string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if rng := r.Header.Get("Range"); rng != "" {
+			ranges = append(ranges, rng)
+
+			if rng == fail {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(contents))
+	}))
+	defer srv.Close()
+
+	// requested returns the byte ranges requested since it was last called.
+
+	requested := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		r := ranges
+		ranges = nil
+		return r
+	}
+
+	dir, err := ioutil.TempDir("", "nicehttp")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "file")
+
+	c := newTestClient(8, WithNumWorkers(1))
+	c.CheckStatus = true
+
+	if err := c.DownloadFileResumable(filename, srv.URL); err == nil {
+		t.Fatalf("expected download to fail")
+	}
+
+	for _, path := range []string{filename + ".part", filename + ".part.meta"} {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("expected %q to be kept for the download to be resumed: %s", path, err)
+		}
+	}
+
+	// A single worker downloads chunks in order, such that all chunks before the one that failed were completed.
+
+	if got := len(requested()); got != 5 {
+		t.Fatalf("expected 5 byte ranges to be requested before the download failed, got %d", got)
+	}
+
+	mu.Lock()
+	fail = ""
+	mu.Unlock()
+
+	if err := c.DownloadFileResumable(filename, srv.URL); err != nil {
+		t.Fatalf("failed to resume download: %s", err)
+	}
+
+	got := requested()
+	if len(got) != 4 || got[0] != "bytes=32-39" {
+		t.Fatalf("expected only the 4 missing byte ranges to be requested, got %v", got)
+	}
+
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("failed to read downloaded file: %s", err)
+	}
+	if !bytes.Equal(buf, contents) {
+		t.Fatalf("downloaded contents do not match")
+	}
+
+	for _, path := range []string{filename + ".part", filename + ".part.meta"} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("expected %q to be removed once the download completed, got %v", path, err)
+		}
+	}
+}
+
+func TestDownloadFileResumableStartsOverOnChange(t *testing.T) {
+	contents := testContents(64)
+
+	var (
+		mu     sync.Mutex
+		etag   = `"v1"`
+		fail   = "bytes=32-39"
+		ranged int
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if rng := r.Header.Get("Range"); rng != "" {
+			ranged++
+
+			if rng == fail {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(contents))
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "nicehttp")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "file")
+
+	c := newTestClient(8, WithNumWorkers(1))
+	c.CheckStatus = true
+
+	if err := c.DownloadFileResumable(filename, srv.URL); err == nil {
+		t.Fatalf("expected download to fail")
+	}
+
+	// Contents that report a different ETag must not be resumed against the chunks downloaded beforehand.
+
+	mu.Lock()
+	etag, fail, ranged = `"v2"`, "", 0
+	mu.Unlock()
+
+	if err := c.DownloadFileResumable(filename, srv.URL); err != nil {
+		t.Fatalf("failed to download: %s", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if ranged != len(contents)/8 {
+		t.Fatalf("expected all %d byte ranges to be requested again, got %d", len(contents)/8, ranged)
+	}
+}
@@ -0,0 +1,163 @@
+package nicehttp
+
+import (
+	"sort"
+	"sync"
+)
+
+const (
+	// minSplitChunkSize is the min size of a byte range that is split in half as a download nears completion.
+	minSplitChunkSize = 512 * 1024
+
+	// minAutoChunkSize and maxAutoChunkSize bound the size of chunks picked by Client.AutoChunkSize.
+	minAutoChunkSize = 1024 * 1024
+	maxAutoChunkSize = 64 * 1024 * 1024
+
+	// autoChunkSizeAlignment is the multiple that chunk sizes picked by Client.AutoChunkSize are rounded up to.
+	autoChunkSizeAlignment = 64 * 1024
+)
+
+var _ Scheduler = SequentialScheduler{}
+
+// ByteRange represents the byte range [Start, End) of the contents of a URL.
+type ByteRange struct {
+	Start int
+	End   int
+}
+
+// Scheduler decides which byte ranges the contents of a URL are downloaded in, and in which order they are
+// downloaded. Byte ranges are handed out to workers in the order they are returned. Resumable downloads require that
+// every byte range starts at a multiple of chunkSize.
+type Scheduler interface {
+	Schedule(length, chunkSize int) []ByteRange
+}
+
+// SequentialScheduler schedules the contents of a URL to be downloaded in consecutive byte ranges of chunkSize bytes,
+// from the start of the contents to their end.
+type SequentialScheduler struct{}
+
+// Schedule implements Scheduler.
+func (SequentialScheduler) Schedule(length, chunkSize int) []ByteRange {
+	if length <= 0 || chunkSize <= 0 {
+		return nil
+	}
+
+	ranges := make([]ByteRange, 0, (length+chunkSize-1)/chunkSize)
+
+	for start := 0; start < length; start += chunkSize {
+		end := start + chunkSize
+		if end > length {
+			end = length
+		}
+
+		ranges = append(ranges, ByteRange{Start: start, End: end})
+	}
+
+	return ranges
+}
+
+// chunkSize returns the size of chunks to download contents comprised of length bytes in. It is c.ChunkSize unless
+// c.AutoChunkSize is set, in which case length is split evenly across c.NumWorkers chunks within sensible bounds.
+func (c *Client) chunkSize(length int) int {
+	if !c.AutoChunkSize || length <= 0 {
+		return c.ChunkSize
+	}
+
+	numWorkers := c.NumWorkers
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+
+	size := (length + numWorkers - 1) / numWorkers
+	size = (size + autoChunkSizeAlignment - 1) / autoChunkSizeAlignment * autoChunkSizeAlignment
+
+	if size < minAutoChunkSize {
+		size = minAutoChunkSize
+	}
+	if size > maxAutoChunkSize {
+		size = maxAutoChunkSize
+	}
+
+	return size
+}
+
+// scheduler returns the scheduler of c, defaulting to SequentialScheduler should c.Scheduler not be set.
+func (c *Client) scheduler() Scheduler {
+	if c.Scheduler == nil {
+		return SequentialScheduler{}
+	}
+	return c.Scheduler
+}
+
+// rangePool hands out byte ranges to workers in the order they were scheduled in. Once fewer byte ranges remain than
+// there are workers, byte ranges are split in half before they are handed out, such that idle workers may pick up the
+// remaining half rather than have a single worker download a large byte range while all other workers sit idle.
+type rangePool struct {
+	mu      sync.Mutex
+	ranges  []ByteRange
+	workers int
+	split   bool
+}
+
+// newRangePool instantiates a new pool of byte ranges to be claimed by workers. Byte ranges are only split up should
+// split be true.
+func newRangePool(ranges []ByteRange, workers int, split bool) *rangePool {
+	return &rangePool{ranges: ranges, workers: workers, split: split}
+}
+
+// next claims the next unclaimed byte range. It returns false should there be no byte ranges left.
+func (p *rangePool) next() (ByteRange, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.ranges) == 0 {
+		return ByteRange{}, false
+	}
+
+	r := p.ranges[0]
+	p.ranges = p.ranges[1:]
+
+	if p.split && len(p.ranges) < p.workers && r.End-r.Start >= 2*minSplitChunkSize {
+		mid := r.Start + (r.End-r.Start)/2
+
+		p.ranges = append([]ByteRange{{Start: mid, End: r.End}}, p.ranges...)
+		r.End = mid
+	}
+
+	return r, true
+}
+
+// subtractRanges returns the portions of ranges that are not covered by any of done, ordered by their start. The
+// byte ranges in done are expected to not overlap.
+func subtractRanges(ranges, done []ByteRange) []ByteRange {
+	sorted := make([]ByteRange, len(done))
+	copy(sorted, done)
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	var missing []ByteRange
+
+	for _, r := range ranges {
+		start := r.Start
+
+		for _, d := range sorted {
+			if d.End <= start || d.Start >= r.End {
+				continue
+			}
+			if d.Start > start {
+				missing = append(missing, ByteRange{Start: start, End: d.Start})
+			}
+			if d.End > start {
+				start = d.End
+			}
+		}
+
+		if start < r.End {
+			missing = append(missing, ByteRange{Start: start, End: r.End})
+		}
+	}
+
+	sort.Slice(missing, func(i, j int) bool { return missing[i].Start < missing[j].Start })
+
+	return missing
+}
@@ -0,0 +1,167 @@
+package nicehttp
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"time"
+)
+
+// DownloadSmart downloads the contents of url into w without first querying its headers. The first chunk of the
+// contents of url is requested straight away. Should url respond with only said chunk and report that its contents
+// are larger than it, the rest of its contents are downloaded in chunks using multiple workers. Otherwise, the
+// response is written to w as-is.
+//
+// Small files are downloaded using a single request, while large files are still downloaded in parallel chunks,
+// without either having to wait for a separate HEAD request to complete first.
+func (c *Client) DownloadSmart(w Writer, url string) error {
+	return c.DownloadSmartDeadline(w, url, zeroTime)
+}
+
+// DownloadSmartTimeout downloads the contents of url into w without first querying its headers, and downloads the
+// rest of its contents in chunks should they be larger than the first chunk.
+func (c *Client) DownloadSmartTimeout(w Writer, url string, timeout time.Duration) error {
+	return c.DownloadSmartDeadline(w, url, c.clock().Add(timeout))
+}
+
+// DownloadSmartDeadline downloads the contents of url into w without first querying its headers, and downloads the
+// rest of its contents in chunks should they be larger than the first chunk.
+func (c *Client) DownloadSmartDeadline(w Writer, url string, deadline time.Time) (err error) {
+	c, done := c.trackStats(url)
+	defer func() { done(err) }()
+
+	if !c.AcceptsRanges || c.ChunkSize <= 0 || c.advertisesEncoding() {
+		c.event(Started{URL: url})
+		return c.downloadSerially(w, url, 0, deadline, nil)
+	}
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	res := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(res)
+
+	req.SetRequestURI(url)
+	req.Header.SetByteRange(0, c.ChunkSize-1)
+
+	if err := c.DoDeadline(req, res, deadline); err != nil {
+		return fmt.Errorf("failed to download %q: %w", url, err)
+	}
+
+	if res.StatusCode() == fasthttp.StatusNotModified {
+		return ErrNotModified
+	}
+
+	if err := c.checkStatus(res); err != nil {
+		return fmt.Errorf("failed to download %q: %w", url, err)
+	}
+
+	// The server ignored the byte range requested, and responded with the entirety of the contents of url.
+
+	if res.StatusCode() != fasthttp.StatusPartialContent {
+		c.event(Started{URL: url, ContentLength: res.Header.ContentLength()})
+
+		total := c.decodedLength(res, 0)
+
+		n, err := c.writeBody(c.throttleWriter(c.progressWriter(w, total), deadline), res)
+		if err != nil {
+			return err
+		}
+
+		c.wrote(n)
+
+		if total == 0 {
+			c.event(Progress{Done: n, Total: n})
+		}
+
+		return nil
+	}
+
+	start, end, total, ok := parseContentRange(res.Header.Peek("Content-Range"))
+	if !ok || start != 0 {
+		return fmt.Errorf("failed to download %q: unexpected Content-Range %q", url, res.Header.Peek("Content-Range"))
+	}
+
+	if n := len(res.Body()); n != end-start {
+		return fmt.Errorf("failed to download %q: got %d byte(s), expected %d: %w", url, n, end-start, ErrShortDownload)
+	}
+
+	if total < 0 {
+		return fmt.Errorf("failed to download %q: content length is unknown", url)
+	}
+
+	c.event(Started{URL: url, ContentLength: total, Chunked: end < total})
+
+	if _, err := c.throttleWriterAt(w, deadline).WriteAt(res.Body(), 0); err != nil {
+		return fmt.Errorf("failed to write first chunk of %q: %w", url, err)
+	}
+
+	c.wrote(int64(end))
+
+	c.event(Progress{Done: int64(end), Total: int64(total)})
+
+	if end >= total {
+		return nil
+	}
+
+	return c.downloadInChunks(w, []string{url}, total, deadline, prefixTracker(end))
+}
+
+var _ chunkTracker = prefixTracker(0)
+
+// prefixTracker is a chunkTracker which reports all byte ranges that lie within its first n bytes as having already
+// been downloaded.
+type prefixTracker int
+
+func (p prefixTracker) completed(_, end int) bool { return end <= int(p) }
+func (p prefixTracker) complete(_, _ int) error   { return nil }
+
+// parseContentRange parses the value of a Content-Range header of the form "bytes start-end/total" into a half-open
+// byte range [start, end). total is -1 should the value report the total length of the contents to be unknown.
+func parseContentRange(value []byte) (start, end, total int, ok bool) {
+	value = bytes.TrimSpace(value)
+
+	if !bytes.HasPrefix(value, []byte("bytes ")) {
+		return 0, 0, 0, false
+	}
+	value = bytes.TrimSpace(value[len("bytes "):])
+
+	slash := bytes.IndexByte(value, '/')
+	if slash < 0 {
+		return 0, 0, 0, false
+	}
+
+	rng, size := value[:slash], value[slash+1:]
+
+	if string(size) == "*" {
+		total = -1
+	} else {
+		n, err := strconv.Atoi(string(size))
+		if err != nil || n < 0 {
+			return 0, 0, 0, false
+		}
+		total = n
+	}
+
+	dash := bytes.IndexByte(rng, '-')
+	if dash < 0 {
+		return 0, 0, 0, false
+	}
+
+	first, err := strconv.Atoi(string(rng[:dash]))
+	if err != nil || first < 0 {
+		return 0, 0, 0, false
+	}
+
+	last, err := strconv.Atoi(string(rng[dash+1:]))
+	if err != nil || last < first {
+		return 0, 0, 0, false
+	}
+
+	if total >= 0 && last >= total {
+		return 0, 0, 0, false
+	}
+
+	return first, last + 1, total, true
+}
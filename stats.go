@@ -0,0 +1,100 @@
+package nicehttp
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// DownloadStats are statistics of a single download, reported to Client.OnComplete once the download has either
+// completed or failed.
+type DownloadStats struct {
+	// URL that was downloaded.
+	URL string
+
+	// Number of response body bytes written.
+	Bytes int64
+
+	// Wall-clock duration of the download.
+	Duration time.Duration
+
+	// Number of chunks downloaded. Zero should the download have been made serially.
+	Chunks int
+
+	// Number of requests that were retried.
+	Retries int
+
+	// Whether or not the contents were downloaded in parallel chunks, rather than serially. It reports the path the
+	// download took, regardless of whether any chunks were downloaded before the download failed.
+	Chunked bool
+
+	// Error the download failed with, if any.
+	Err error
+}
+
+// downloadStats are counters shared across all workers of a single download.
+type downloadStats struct {
+	bytes   int64
+	chunks  int64
+	retries int64
+	chunked int32
+}
+
+// trackStats returns a copy of c which records statistics of a download of url, alongside a func to be called once
+// the download completes with the error it failed with, if any, which reports said statistics to c.OnComplete. It
+// returns c as-is should c.OnComplete be nil, or should c already be recording statistics of a download.
+func (c *Client) trackStats(url string) (*Client, func(err error)) {
+	if c.OnComplete == nil || c.stats != nil {
+		return c, func(error) {}
+	}
+
+	cc := *c
+	cc.stats = new(downloadStats)
+
+	start := c.clock()
+
+	return &cc, func(err error) {
+		chunks := atomic.LoadInt64(&cc.stats.chunks)
+
+		c.OnComplete(DownloadStats{
+			URL:      url,
+			Bytes:    atomic.LoadInt64(&cc.stats.bytes),
+			Duration: c.clock().Sub(start),
+			Chunks:   int(chunks),
+			Retries:  int(atomic.LoadInt64(&cc.stats.retries)),
+			Chunked:  atomic.LoadInt32(&cc.stats.chunked) == 1,
+			Err:      err,
+		})
+	}
+}
+
+// tookPath records whether the download in progress is being downloaded in parallel chunks, or serially.
+func (c *Client) tookPath(chunked bool) {
+	if c.stats == nil {
+		return
+	}
+
+	var v int32
+	if chunked {
+		v = 1
+	}
+
+	atomic.StoreInt32(&c.stats.chunked, v)
+}
+
+// wrote records that n bytes of the contents of the download in progress have been written.
+func (c *Client) wrote(n int64) {
+	if c.stats != nil {
+		atomic.AddInt64(&c.stats.bytes, n)
+	}
+	if c.written != nil {
+		atomic.AddInt64(c.written, n)
+	}
+}
+
+// countWritten returns a copy of c which counts the number of bytes of the contents of a download that are written
+// into the returned counter.
+func (c *Client) countWritten() (*Client, *int64) {
+	cc := *c
+	cc.written = new(int64)
+	return &cc, cc.written
+}
@@ -0,0 +1,51 @@
+package nicehttp
+
+import (
+	"errors"
+	"github.com/valyala/fasthttp"
+	"testing"
+)
+
+func TestStatsReportPathTaken(t *testing.T) {
+	contents := testContents(64)
+
+	t.Run("chunked download failing before any chunk completes", func(t *testing.T) {
+		errFailed := errors.New("failed")
+
+		c := WrapClient(transportFunc(func(req *fasthttp.Request, res *fasthttp.Response) error {
+			return errFailed
+		}))
+		c.NumWorkers = 2
+		c.ChunkSize = 8
+
+		var stats DownloadStats
+		c.OnComplete = func(s DownloadStats) { stats = s }
+
+		buf := NewWriteBuffer(make([]byte, len(contents)))
+		if err := c.DownloadInChunks(buf, "http://example.com", len(contents)); !errors.Is(err, errFailed) {
+			t.Fatalf("expected download to fail, got %v", err)
+		}
+
+		if !stats.Chunked || stats.Chunks != 0 {
+			t.Fatalf("expected a chunked download with no chunks completed, got %+v", stats)
+		}
+	})
+
+	t.Run("serial download", func(t *testing.T) {
+		srv := newContentServer(t, contents, nil)
+
+		c := newTestClient(8)
+		c.AcceptsRanges = false
+
+		var stats DownloadStats
+		c.OnComplete = func(s DownloadStats) { stats = s }
+
+		if _, err := c.DownloadBytes(nil, srv.URL); err != nil {
+			t.Fatalf("failed to download: %s", err)
+		}
+
+		if stats.Chunked || stats.Bytes != int64(len(contents)) {
+			t.Fatalf("expected a serial download of %d byte(s), got %+v", len(contents), stats)
+		}
+	})
+}
@@ -0,0 +1,248 @@
+package nicehttp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// DownloadStream downloads the contents of url comprised of length bytes in chunks using multiple workers, and writes
+// them to w in order. Chunks that are downloaded ahead of the next chunk to be written to w are buffered in memory
+// until all chunks before them have been written to w.
+//
+// At most c.MaxBufferedChunks chunks are buffered in memory at once. Workers that have downloaded a chunk past said
+// limit wait for the chunks before it to be written to w before writing it.
+func (c *Client) DownloadStream(w io.Writer, url string, length int) error {
+	return c.DownloadStreamDeadline(w, url, length, zeroTime)
+}
+
+// DownloadStreamTimeout downloads the contents of url comprised of length bytes in chunks using multiple workers,
+// and writes them to w in order.
+func (c *Client) DownloadStreamTimeout(w io.Writer, url string, length int, timeout time.Duration) error {
+	return c.DownloadStreamDeadline(w, url, length, c.clock().Add(timeout))
+}
+
+// DownloadStreamDeadline downloads the contents of url comprised of length bytes in chunks using multiple workers,
+// and writes them to w in order.
+func (c *Client) DownloadStreamDeadline(w io.Writer, url string, length int, deadline time.Time) error {
+	window := c.MaxBufferedChunks
+	if window < 1 {
+		window = c.NumWorkers
+	}
+	if window < 1 {
+		window = 1
+	}
+
+	o := newOrderedWriter(w, int64(window)*int64(c.chunkSize(length)))
+
+	if err := c.downloadInChunks(o, []string{url}, length, deadline, nil); err != nil {
+		return err
+	}
+
+	if o.next != int64(length) {
+		return fmt.Errorf("failed to stream %q: wrote %d byte(s), expected %d: %w", url, o.next, length, ErrShortDownload)
+	}
+
+	return nil
+}
+
+// OpenStream downloads the contents of url in chunks using multiple workers in the background, and returns a reader
+// which yields the contents in order as the chunks before them complete, such that the contents may be processed as
+// they are downloaded without being buffered in memory in their entirety. Contents that may not be downloaded in
+// chunks are downloaded serially into the reader instead.
+//
+// Reading from the reader applies backpressure to the workers: at most c.MaxBufferedChunks chunks are buffered in
+// memory at once, past which workers wait for the reader to catch up. The error the download fails with is returned
+// by Read once all contents before the failure have been read. The reader must be closed once it is no longer
+// needed, which stops the download should it still be in progress.
+func (c *Client) OpenStream(url string) (io.ReadCloser, error) {
+	return c.OpenStreamDeadline(url, zeroTime)
+}
+
+// OpenStreamTimeout downloads the contents of url in chunks using multiple workers in the background, and returns a
+// reader which yields the contents in order. The timeout bounds both the download and the reads made from the reader.
+func (c *Client) OpenStreamTimeout(url string, timeout time.Duration) (io.ReadCloser, error) {
+	return c.OpenStreamDeadline(url, c.clock().Add(timeout))
+}
+
+// OpenStreamDeadline downloads the contents of url in chunks using multiple workers in the background, and returns a
+// reader which yields the contents in order. The deadline bounds both the download and the reads made from the
+// reader.
+func (c *Client) OpenStreamDeadline(url string, deadline time.Time) (io.ReadCloser, error) {
+	return c.openStream(url, deadline)
+}
+
+// openStream starts downloading the contents of url in the background into the returned reader.
+func (c *Client) openStream(url string, deadline time.Time) (*streamReader, error) {
+	info, err := c.queryInfo(url, deadline, nil)
+	if queryFailed(err) {
+		return nil, fmt.Errorf("failed to open %q: %w", url, err)
+	}
+
+	c = c.forTarget(url, info)
+
+	target := info.target(url)
+
+	pr, pw := io.Pipe()
+
+	s := &streamReader{PipeReader: pr, started: make(chan struct{}), done: make(chan struct{})}
+
+	w := &startWriter{Writer: pw, started: s.started}
+
+	go func() {
+		defer close(s.done)
+
+		if c.AcceptsRanges && info.acceptsRanges && info.contentLength > 0 {
+			s.err = c.DownloadStreamDeadline(w, target, info.contentLength, deadline)
+		} else {
+			s.err = c.downloadSerially(w, target, info.contentLength, deadline, nil)
+		}
+
+		pw.CloseWithError(s.err)
+	}()
+
+	return s, nil
+}
+
+// streamReader is the reader of a download running in the background. started is closed once the first bytes of the
+// download are written, and done once the download completes, after which err holds the error it failed with.
+type streamReader struct {
+	*io.PipeReader
+
+	started chan struct{}
+	done    chan struct{}
+	err     error
+}
+
+// Close implements io.Closer. It stops the download should it still be in progress, and waits for its workers to
+// return.
+func (s *streamReader) Close() error {
+	err := s.PipeReader.Close()
+	<-s.done
+	return err
+}
+
+// aborter is implemented by writers that may block, and that must be woken up should the download writing to them
+// fail.
+type aborter interface {
+	abort(err error)
+}
+
+var (
+	_ io.WriterAt = (*orderedWriter)(nil)
+	_ aborter     = (*orderedWriter)(nil)
+)
+
+// orderedWriter is an io.WriterAt which writes to an io.Writer in order. Writes made past the offset of the next
+// byte to be written are buffered until all bytes before them have been written. Once more than limit bytes are
+// buffered, writes made past said offset block until enough buffered bytes have been written.
+type orderedWriter struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	w     io.Writer
+	next  int64
+	limit int64
+
+	pending  map[int64][]byte
+	buffered int64
+
+	err error
+}
+
+func newOrderedWriter(w io.Writer, limit int64) *orderedWriter {
+	o := &orderedWriter{w: w, limit: limit, pending: make(map[int64][]byte)}
+	o.cond = sync.NewCond(&o.mu)
+	return o
+}
+
+func (o *orderedWriter) WriteAt(p []byte, off int64) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.err != nil {
+		return 0, o.err
+	}
+
+	if off < o.next {
+		return 0, fmt.Errorf("write at offset %d is behind the next offset to be written %d", off, o.next)
+	}
+
+	if off > o.next {
+		if _, exists := o.pending[off]; exists {
+			return 0, fmt.Errorf("write at offset %d has already been buffered", off)
+		}
+
+		for o.err == nil && off != o.next && o.buffered > 0 && o.buffered+int64(len(p)) > o.limit {
+			o.cond.Wait()
+		}
+
+		if o.err != nil {
+			return 0, o.err
+		}
+
+		if off != o.next {
+			o.pending[off] = append([]byte(nil), p...)
+			o.buffered += int64(len(p))
+
+			return len(p), nil
+		}
+	}
+
+	if err := o.flush(p); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// flush writes p, which is to be written at the offset of the next byte to be written, alongside all buffered bytes
+// that directly follow it. It must be called with o.mu held.
+func (o *orderedWriter) flush(p []byte) error {
+	for {
+		n, err := o.w.Write(p)
+		o.next += int64(n)
+
+		if err == nil && n != len(p) {
+			err = io.ErrShortWrite
+		}
+
+		if err != nil {
+			o.err = err
+			o.cond.Broadcast()
+
+			return err
+		}
+
+		buf, ok := o.pending[o.next]
+		if !ok {
+			break
+		}
+
+		delete(o.pending, o.next)
+		o.buffered -= int64(len(buf))
+
+		p = buf
+	}
+
+	o.cond.Broadcast()
+
+	return nil
+}
+
+func (o *orderedWriter) abort(err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if err == nil {
+		err = errors.New("download aborted")
+	}
+
+	if o.err == nil {
+		o.err = err
+	}
+
+	o.cond.Broadcast()
+}
@@ -0,0 +1,140 @@
+package nicehttp
+
+import (
+	"github.com/valyala/fasthttp"
+	"io"
+	"sync"
+	"time"
+)
+
+var (
+	_ io.Writer   = (*throttledWriter)(nil)
+	_ io.WriterAt = (*throttledWriterAt)(nil)
+)
+
+// tokenBucket is a token bucket whose capacity and refill rate are both the number of tokens permitted per second.
+type tokenBucket struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// reserve takes n tokens from the bucket given a refill rate of rate tokens per second, and returns how long the
+// caller must wait before the tokens it has taken are considered to be available, given that it is currently now.
+func (b *tokenBucket) reserve(n int, rate int64, now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.last.IsZero() {
+		b.tokens = float64(rate)
+	} else if b.tokens += now.Sub(b.last).Seconds() * float64(rate); b.tokens > float64(rate) {
+		b.tokens = float64(rate)
+	}
+
+	b.last = now
+	b.tokens -= float64(n)
+
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / float64(rate) * float64(time.Second))
+}
+
+// throttle blocks until n bytes may be written without exceeding c.MaxBytesPerSecond. It returns fasthttp.ErrTimeout
+// should the bytes only be able to be written past deadline, and ErrCanceled should the download in progress be
+// canceled while it waits.
+func (c *Client) throttle(n int, deadline time.Time) error {
+	delay := c.state.bucket.reserve(n, c.MaxBytesPerSecond, c.clock())
+	if delay <= 0 {
+		return nil
+	}
+
+	if !deadline.IsZero() && c.clock().Add(delay).After(deadline) {
+		return fasthttp.ErrTimeout
+	}
+
+	return c.sleep(delay)
+}
+
+// throttleWriter wraps w such that writes to it are rate-limited by c.MaxBytesPerSecond. It returns w as-is should
+// no limit be set.
+func (c *Client) throttleWriter(w io.Writer, deadline time.Time) io.Writer {
+	if c.MaxBytesPerSecond <= 0 || c.state == nil {
+		return w
+	}
+	return &throttledWriter{dst: w, client: c, deadline: deadline}
+}
+
+// throttleWriterAt wraps w such that writes to it are rate-limited by c.MaxBytesPerSecond. It returns w as-is should
+// no limit be set.
+func (c *Client) throttleWriterAt(w io.WriterAt, deadline time.Time) io.WriterAt {
+	if c.MaxBytesPerSecond <= 0 || c.state == nil {
+		return w
+	}
+	return &throttledWriterAt{dst: w, client: c, deadline: deadline}
+}
+
+// throttledWriter implements io.Writer, rate-limiting writes to an underlying io.Writer.
+type throttledWriter struct {
+	dst      io.Writer
+	client   *Client
+	deadline time.Time
+}
+
+// Write implements io.Writer.
+func (w *throttledWriter) Write(b []byte) (n int, err error) {
+	for len(b) > 0 {
+		p := b
+		if int64(len(p)) > w.client.MaxBytesPerSecond {
+			p = p[:w.client.MaxBytesPerSecond]
+		}
+
+		if err := w.client.throttle(len(p), w.deadline); err != nil {
+			return n, err
+		}
+
+		nn, err := w.dst.Write(p)
+		n += nn
+
+		if err != nil {
+			return n, err
+		}
+
+		b = b[nn:]
+	}
+
+	return n, nil
+}
+
+// throttledWriterAt implements io.WriterAt, rate-limiting writes to an underlying io.WriterAt.
+type throttledWriterAt struct {
+	dst      io.WriterAt
+	client   *Client
+	deadline time.Time
+}
+
+// WriteAt implements io.WriterAt.
+func (w *throttledWriterAt) WriteAt(b []byte, off int64) (n int, err error) {
+	for len(b) > 0 {
+		p := b
+		if int64(len(p)) > w.client.MaxBytesPerSecond {
+			p = p[:w.client.MaxBytesPerSecond]
+		}
+
+		if err := w.client.throttle(len(p), w.deadline); err != nil {
+			return n, err
+		}
+
+		nn, err := w.dst.WriteAt(p, off+int64(n))
+		n += nn
+
+		if err != nil {
+			return n, err
+		}
+
+		b = b[nn:]
+	}
+
+	return n, nil
+}
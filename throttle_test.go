@@ -0,0 +1,36 @@
+package nicehttp
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestMaxBytesPerSecond(t *testing.T) {
+	contents := testContents(160)
+
+	srv := newContentServer(t, contents, nil)
+
+	// The first second worth of bytes is available up front, such that the remaining 60 bytes take at least 600ms
+	// regardless of how many workers write them.
+
+	for _, acceptsRanges := range []bool{false, true} {
+		c := newTestClient(16)
+		c.AcceptsRanges = acceptsRanges
+		c.MaxBytesPerSecond = 100
+
+		start := time.Now()
+
+		buf, err := c.DownloadBytes(nil, srv.URL)
+		if err != nil {
+			t.Fatalf("failed to download: %s", err)
+		}
+		if !bytes.Equal(buf, contents) {
+			t.Fatalf("downloaded contents do not match")
+		}
+
+		if elapsed := time.Since(start); elapsed < 600*time.Millisecond {
+			t.Fatalf("expected download with AcceptsRanges=%t to take at least 600ms, took %s", acceptsRanges, elapsed)
+		}
+	}
+}
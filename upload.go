@@ -0,0 +1,95 @@
+package nicehttp
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FormField is a form field sent alongside a file uploaded using UploadFile.
+type FormField struct {
+	Name  string
+	Value string
+}
+
+// UploadFile uploads the file titled filename to url as a multipart/form-data POST request, under the form field
+// fieldName alongside fields. The file is streamed from disk rather than being buffered into memory. It fails with a
+// *HTTPError should the response not have a 2xx status code.
+//
+// As the file is streamed, redirects that require the body of the request to be sent again (307 and 308) may not be
+// followed, and fail the upload.
+func (c *Client) UploadFile(url, fieldName, filename string, fields ...FormField) error {
+	return c.UploadFileDeadline(url, fieldName, filename, zeroTime, fields...)
+}
+
+// UploadFileTimeout uploads the file titled filename to url as a multipart/form-data POST request, under the form
+// field fieldName alongside fields.
+func (c *Client) UploadFileTimeout(url, fieldName, filename string, timeout time.Duration, fields ...FormField) error {
+	return c.UploadFileDeadline(url, fieldName, filename, c.clock().Add(timeout), fields...)
+}
+
+// UploadFileDeadline uploads the file titled filename to url as a multipart/form-data POST request, under the form
+// field fieldName alongside fields.
+func (c *Client) UploadFileDeadline(url, fieldName, filename string, deadline time.Time, fields ...FormField) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	// Write out the parts of the body that precede and follow the contents of the file up front, such that the
+	// length of the body is known before the file is streamed.
+
+	var buf bytes.Buffer
+
+	mw := multipart.NewWriter(&buf)
+
+	for _, field := range fields {
+		if err := mw.WriteField(field.Name, field.Value); err != nil {
+			return fmt.Errorf("failed to write form field %q: %w", field.Name, err)
+		}
+	}
+
+	if _, err := mw.CreateFormFile(fieldName, filepath.Base(filename)); err != nil {
+		return fmt.Errorf("failed to write form file header: %w", err)
+	}
+
+	n := buf.Len()
+
+	if err := mw.Close(); err != nil {
+		return fmt.Errorf("failed to write closing boundary: %w", err)
+	}
+
+	head, tail := buf.Bytes()[:n], buf.Bytes()[n:]
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	res := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(res)
+
+	req.Header.SetMethod(fasthttp.MethodPost)
+	req.SetRequestURI(url)
+	req.Header.SetContentType(mw.FormDataContentType())
+	req.SetBodyStream(io.MultiReader(bytes.NewReader(head), f, bytes.NewReader(tail)), len(head)+int(fi.Size())+len(tail))
+
+	if err := c.DoDeadline(req, res, deadline); err != nil {
+		return fmt.Errorf("failed to upload %q to %q: %w", filename, url, err)
+	}
+
+	if status := res.StatusCode(); status < fasthttp.StatusOK || status >= fasthttp.StatusMultipleChoices {
+		return fmt.Errorf("failed to upload %q to %q: %w", filename, url, newHTTPError(res))
+	}
+
+	return nil
+}
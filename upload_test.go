@@ -0,0 +1,134 @@
+package nicehttp
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestUploadFile(t *testing.T) {
+	contents := testContents(64 * 1024)
+
+	dir, err := ioutil.TempDir("", "nicehttp")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "upload.bin")
+	if err := ioutil.WriteFile(filename, contents, 0644); err != nil {
+		t.Fatalf("failed to write file: %s", err)
+	}
+
+	var (
+		uploaded   []byte
+		name       string
+		fields     = make(map[string]string)
+		length     int64
+		expected   int64
+		transfers  []string
+		redirected int32
+	)
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
+		length, transfers = r.ContentLength, r.TransferEncoding
+
+		// Compute the length that the body is expected to have using the boundary that the request was sent with.
+
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var buf bytes.Buffer
+
+		mw := multipart.NewWriter(&buf)
+		if err := mw.SetBoundary(params["boundary"]); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mw.WriteField("a", "1")
+		mw.WriteField("b", "2")
+		mw.CreateFormFile("file", "upload.bin")
+		mw.Close()
+
+		expected = int64(buf.Len() + len(contents))
+
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		for key := range r.MultipartForm.Value {
+			fields[key] = r.MultipartForm.Value[key][0]
+		}
+
+		f, header, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer f.Close()
+
+		name = header.Filename
+
+		if uploaded, err = ioutil.ReadAll(f); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	})
+
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&redirected, 1)
+		http.Redirect(w, r, "/upload", http.StatusTemporaryRedirect)
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := NewClient()
+
+	if err := c.UploadFile(srv.URL+"/upload", "file", filename, FormField{Name: "a", Value: "1"}, FormField{Name: "b", Value: "2"}); err != nil {
+		t.Fatalf("failed to upload: %s", err)
+	}
+
+	if !bytes.Equal(uploaded, contents) {
+		t.Fatalf("uploaded contents do not match")
+	}
+	if name != "upload.bin" {
+		t.Fatalf("expected file part to be named %q, got %q", "upload.bin", name)
+	}
+	if len(fields) != 2 || fields["a"] != "1" || fields["b"] != "2" {
+		t.Fatalf("expected form fields a=1 and b=2, got %v", fields)
+	}
+
+	// The length of the body is known up front, such that the body is not sent chunked.
+
+	if length != expected || len(transfers) != 0 {
+		t.Fatalf("expected a content length of %d byte(s) and no transfer encoding, got %d and %v", expected, length, transfers)
+	}
+
+	// The file may not be streamed again to the URL that a 307 redirects to.
+
+	uploaded = nil
+
+	if err := c.UploadFile(srv.URL+"/redirect", "file", filename); err == nil {
+		t.Fatalf("expected upload to fail upon a 307 redirect")
+	}
+	if atomic.LoadInt32(&redirected) != 1 {
+		t.Fatalf("expected the upload to be sent to the redirecting URL once")
+	}
+	if uploaded != nil {
+		t.Fatalf("expected the upload to not be sent to the URL redirected to")
+	}
+}
@@ -1,13 +1,16 @@
 package nicehttp
 
 import (
+	"errors"
 	"github.com/lithdew/bytesutil"
 	"io"
+	"sync"
 )
 
 var (
-	_ io.Writer = (*WriterAtOffset)(nil)
-	_ Writer    = (*WriteBuffer)(nil)
+	_ io.Writer   = (*WriterAtOffset)(nil)
+	_ io.WriterAt = WriterAtFunc(nil)
+	_ Writer      = (*WriteBuffer)(nil)
 )
 
 // Writer implements io.Writer and io.WriterAt.
@@ -16,7 +19,8 @@ type Writer interface {
 	io.WriterAt
 }
 
-// WriterAtOffset implements io.Writer for a given io.WriterAt at an offset.
+// WriterAtOffset implements io.Writer for a given io.WriterAt at an offset. The offset is advanced by the number of
+// bytes written on every call to Write, such that consecutive writes are laid out contiguously.
 type WriterAtOffset struct {
 	dst    io.WriterAt
 	offset int64
@@ -27,13 +31,80 @@ func NewWriterAtOffset(dst io.WriterAt, offset int64) *WriterAtOffset {
 	return &WriterAtOffset{dst: dst, offset: offset}
 }
 
-// Write implements io.Writer.
-func (w WriterAtOffset) Write(b []byte) (int, error) {
-	return w.dst.WriteAt(b, w.offset)
+// Write implements io.Writer. It returns io.ErrShortWrite should fewer bytes than len(b) have been written.
+func (w *WriterAtOffset) Write(b []byte) (int, error) {
+	n, err := w.dst.WriteAt(b, w.offset)
+	w.offset += int64(n)
+
+	if err == nil && n < len(b) {
+		err = io.ErrShortWrite
+	}
+
+	return n, err
+}
+
+// WriterAtFunc implements io.WriterAt by routing every write to the io.WriterAt returned for the offset the write is
+// made at, which is written to at the local offset returned alongside it. It allows the contents of a download to be
+// sharded or striped across multiple files, i.e. ones on different disks, by passing it to DownloadInChunks in place
+// of a single io.WriterAt.
+//
+// Writes made by workers of a chunked download each cover one chunk, such that the func is called once per chunk with
+// the offset the chunk starts at. Chunks must not straddle the boundary between two writers, which is ensured by
+// having the size of every writer be a multiple of the ChunkSize of the Client. The func may be called from multiple
+// goroutines at once.
+type WriterAtFunc func(offset int64) (w io.WriterAt, localOffset int64, err error)
+
+// WriteAt implements io.WriterAt.
+func (f WriterAtFunc) WriteAt(b []byte, off int64) (int, error) {
+	w, local, err := f(off)
+	if err != nil {
+		return 0, err
+	}
+	return w.WriteAt(b, local)
+}
+
+// defaultCopyBufferSize is the size of the buffers bodies are copied through should Client.CopyBufferSize not be set.
+const defaultCopyBufferSize = 32 * 1024
+
+// copyBufferPool pools the buffers bodies are copied through.
+var copyBufferPool sync.Pool
+
+// acquireCopyBuffer returns a buffer of size bytes from the pool, or of defaultCopyBufferSize bytes should size not
+// be positive. Buffers pooled that are smaller than size are left in the pool for another caller.
+func acquireCopyBuffer(size int) *[]byte {
+	if size <= 0 {
+		size = defaultCopyBufferSize
+	}
+
+	if v := copyBufferPool.Get(); v != nil {
+		buf := v.(*[]byte)
+		if cap(*buf) >= size {
+			*buf = (*buf)[:size]
+			return buf
+		}
+		copyBufferPool.Put(buf)
+	}
+
+	buf := make([]byte, size)
+	return &buf
+}
+
+// releaseCopyBuffer returns buf to the pool.
+func releaseCopyBuffer(buf *[]byte) {
+	copyBufferPool.Put(buf)
 }
 
-// WriteBuffer implements io.Writer and io.WriterAt on an optionally-provided byte slice.
+// ErrBodyTooLarge is returned by WriteBuffer should a write have the buffer grow past its MaxSize.
+var ErrBodyTooLarge = errors.New("body too large")
+
+// WriteBuffer implements io.Writer and io.WriterAt on an optionally-provided byte slice. It is safe to call WriteAt
+// concurrently, such that chunks may be written into it by multiple workers in parallel.
 type WriteBuffer struct {
+	// Max length the underlying byte slice may grow to. Writes that would have it grow past MaxSize fail with
+	// ErrBodyTooLarge, and write nothing. Zero means unlimited.
+	MaxSize int
+
+	mu  sync.RWMutex
 	dst []byte
 }
 
@@ -44,13 +115,39 @@ func NewWriteBuffer(dst []byte) *WriteBuffer {
 
 // Write implements io.Writer.
 func (b *WriteBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.MaxSize > 0 && len(b.dst)+len(p) > b.MaxSize {
+		return 0, ErrBodyTooLarge
+	}
+
 	b.dst = append(b.dst, p...)
 	return len(p), nil
 }
 
-// WriteAt implements io.WriterAt.
+// WriteAt implements io.WriterAt. Writes that fit within the buffer are copied in place while only holding a read
+// lock, such that concurrent writes to disjoint offsets do not contend with each other. Writes that require the
+// buffer to grow hold a write lock.
 func (b *WriteBuffer) WriteAt(p []byte, off int64) (int, error) {
-	if min := int(off) + len(p); min > len(b.dst) {
+	min := int(off) + len(p)
+
+	if b.MaxSize > 0 && min > b.MaxSize {
+		return 0, ErrBodyTooLarge
+	}
+
+	b.mu.RLock()
+	if min <= len(b.dst) {
+		n := copy(b.dst[off:], p)
+		b.mu.RUnlock()
+		return n, nil
+	}
+	b.mu.RUnlock()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if min > len(b.dst) {
 		b.dst = bytesutil.ExtendSlice(b.dst, min)
 	}
 	return copy(b.dst[off:], p), nil
@@ -58,5 +155,25 @@ func (b *WriteBuffer) WriteAt(p []byte, off int64) (int, error) {
 
 // Bytes returns the underlying byte slice.
 func (b *WriteBuffer) Bytes() []byte {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
 	return b.dst
 }
+
+// Len returns the length of the underlying byte slice.
+func (b *WriteBuffer) Len() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return len(b.dst)
+}
+
+// Reset truncates the underlying byte slice to a length of zero while keeping its capacity, such that the buffer may
+// be reused.
+func (b *WriteBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.dst = b.dst[:0]
+}
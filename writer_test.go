@@ -0,0 +1,117 @@
+package nicehttp
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"testing"
+)
+
+func TestWriteBufferConcurrentWriteAt(t *testing.T) {
+	contents := testContents(1024)
+
+	// Write chunks from the back of the buffer to the front, such that the buffer grows while other chunks are being
+	// copied into it.
+
+	b := NewWriteBuffer(nil)
+
+	var wg sync.WaitGroup
+	for off := len(contents) - 16; off >= 0; off -= 16 {
+		wg.Add(1)
+
+		go func(off int) {
+			defer wg.Done()
+
+			if _, err := b.WriteAt(contents[off:off+16], int64(off)); err != nil {
+				t.Errorf("failed to write at offset %d: %s", off, err)
+			}
+		}(off)
+	}
+	wg.Wait()
+
+	if !bytes.Equal(b.Bytes(), contents) {
+		t.Fatalf("written contents do not match")
+	}
+}
+
+func TestDownloadBytesInChunks(t *testing.T) {
+	contents := testContents(1024)
+
+	var log requestLog
+
+	srv := newContentServer(t, contents, &log)
+
+	c := newTestClient(16, WithNumWorkers(8))
+
+	buf, err := c.DownloadBytes(nil, srv.URL)
+	if err != nil {
+		t.Fatalf("failed to download: %s", err)
+	}
+	if !bytes.Equal(buf, contents) {
+		t.Fatalf("downloaded contents do not match")
+	}
+
+	var ranged int
+	for _, req := range log.all() {
+		if req.Range != "" {
+			ranged++
+		}
+	}
+
+	if ranged != len(contents)/16 {
+		t.Fatalf("expected %d ranged requests, got %d", len(contents)/16, ranged)
+	}
+}
+
+func TestWriterAtOffset(t *testing.T) {
+	contents := testContents(64)
+
+	b := NewWriteBuffer(make([]byte, 8+len(contents)))
+	w := NewWriterAtOffset(b, 8)
+
+	for _, piece := range [][]byte{contents[:5], contents[5:32], contents[32:33], contents[33:]} {
+		n, err := w.Write(piece)
+		if err != nil || n != len(piece) {
+			t.Fatalf("failed to write %d byte(s): wrote %d, %v", len(piece), n, err)
+		}
+	}
+
+	if !bytes.Equal(b.Bytes()[:8], make([]byte, 8)) || !bytes.Equal(b.Bytes()[8:], contents) {
+		t.Fatalf("expected pieces to be written contiguously from offset 8")
+	}
+}
+
+// shortWriterAt is an io.WriterAt which writes at most max bytes at a time without returning an error.
+type shortWriterAt struct {
+	dst io.WriterAt
+	max int
+}
+
+func (w shortWriterAt) WriteAt(b []byte, off int64) (int, error) {
+	if len(b) > w.max {
+		b = b[:w.max]
+	}
+	return w.dst.WriteAt(b, off)
+}
+
+func TestWriterAtOffsetShortWrite(t *testing.T) {
+	contents := testContents(16)
+
+	b := NewWriteBuffer(nil)
+	w := NewWriterAtOffset(shortWriterAt{dst: b, max: 10}, 0)
+
+	n, err := w.Write(contents)
+	if err != io.ErrShortWrite || n != 10 {
+		t.Fatalf("expected a short write of 10 byte(s), got %d byte(s) and %v", n, err)
+	}
+
+	// The offset is only advanced by the number of bytes written, such that the rest may be written afterwards.
+
+	if _, err := w.Write(contents[n:]); err != nil {
+		t.Fatalf("failed to write rest of contents: %s", err)
+	}
+
+	if !bytes.Equal(b.Bytes(), contents) {
+		t.Fatalf("written contents do not match")
+	}
+}